@@ -31,6 +31,7 @@ func (a *App) initializeAcceptanceRoutes() {
 	a.Router.HandleFunc("/acceptance/{id}", a.getAcceptance).Methods("GET")
 	a.Router.HandleFunc("/acceptance/{id}", a.updateAcceptance).Methods("PUT")
 	a.Router.HandleFunc("/acceptance/{id}", a.deleteAcceptance).Methods("DELETE")
+	a.Router.HandleFunc("/acceptance/{id}/restore", a.restoreAcceptance).Methods("POST")
 	a.Router.HandleFunc("/post/image", a.PostImage).Methods("POST")
 	a.Router.HandleFunc("/load/image", a.LoadImage).Methods("GET")
 	a.Router.HandleFunc("/profit", a.getProfit).Methods("GET")
@@ -94,7 +95,8 @@ func (a *App) getAcceptances(w http.ResponseWriter, r *http.Request) {
 	app.RespondWithJSON(w, http.StatusOK, acceptance)
 }
 
-// Inserts new acceptance into db.
+// Accepts returned book of the reader's open loan. Creates acceptance with
+// the final cost and closes the loan.
 func (a *App) createAcceptance(w http.ResponseWriter, r *http.Request) {
 	var dt model.Acceptance
 	// Gets JSON object from request body.
@@ -107,6 +109,15 @@ func (a *App) createAcceptance(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	if err := dt.CreateAcceptance(d.Database); err != nil {
+		switch err.(type) {
+		case *model.UnknownConditionError, *model.UnknownDamageError:
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err == model.ErrNoOpenLoan || err == model.ErrBranchNotFound {
+			app.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -135,7 +146,22 @@ func (a *App) updateAcceptance(w http.ResponseWriter, r *http.Request) {
 	dt.ID = id
 
 	if err := dt.UpdateAcceptance(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err.(type) {
+		case *model.UnknownConditionError, *model.UnknownDamageError:
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		switch err {
+		case model.ErrClientCost:
+			// Respond with 400 if the payload changes what the return worked out.
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+		case sql.ErrNoRows:
+			// Respond with 404 if acceptance not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Acceptance not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with updated acceptance.
@@ -153,7 +179,41 @@ func (a *App) deleteAcceptance(w http.ResponseWriter, r *http.Request) {
 
 	dt := model.Acceptance{ID: id}
 	if err := dt.DeleteAcceptance(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if acceptance not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Acceptance not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+
+// Restores soft deleted acceptance in db using id from URL.
+func (a *App) restoreAcceptance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// Convert id string variable to int.
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid acceptance ID")
+		return
+	}
+
+	dt := model.Acceptance{ID: id}
+	if err := dt.RestoreAcceptance(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if there is no deleted acceptance with the id.
+			app.RespondWithError(w, http.StatusNotFound, "Deleted acceptance not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with success message if operation is completed.
@@ -35,6 +35,7 @@ func (a *App) initializeAdminRoutes() {
 	a.Router.HandleFunc("/admin/{id}", a.getAdmin).Methods("GET")
 	a.Router.HandleFunc("/admin/{id}", a.updateAdmin).Methods("PUT")
 	a.Router.HandleFunc("/admin/{id}", a.deleteAdmin).Methods("DELETE")
+	a.Router.HandleFunc("/admin/{id}/privileged", a.setAdminPrivileged).Methods("PUT")
 }
 
 // Route handlers
@@ -63,7 +64,7 @@ func (a *App) loginAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Generate and send token to client with response header.
-	validToken, err := GenerateJWT()
+	validToken, err := GenerateAdminJWT(u)
 	if err != nil {
 		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
 	}
@@ -209,3 +210,101 @@ func GenerateJWT() (string, error) {
 
 	return tokenString, nil
 }
+
+// Grants or revokes the privileged role of admin from URL. Only privileged
+// admins may do so; the first ones are listed in PRIVILEGED_ADMINS.
+func (a *App) setAdminPrivileged(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid admin ID")
+		return
+	}
+	requester, ok := privilegedAdmin(w, r)
+	if !ok {
+		return
+	}
+	if requester.ID == id {
+		app.RespondWithError(w, http.StatusConflict, "admins cannot change their own role")
+		return
+	}
+
+	var u model.Admin
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&u); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+	u.ID = id
+
+	if err := u.SetPrivileged(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			app.RespondWithError(w, http.StatusNotFound, "Admin not found")
+		default:
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"id": id, "privileged": u.Privileged})
+}
+
+// Returns the admin making the request if they are privileged. Responds
+// with 401 or 403 and reports false otherwise.
+func privilegedAdmin(w http.ResponseWriter, r *http.Request) (model.Admin, bool) {
+	u, ok := requestAdmin(r)
+	if !ok {
+		app.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return u, false
+	}
+	if err := u.GetAdmin(d.Database); err != nil || !u.Privileged {
+		app.RespondWithError(w, http.StatusForbidden, model.ErrNotPrivileged.Error())
+		return u, false
+	}
+	return u, true
+}
+
+// Generate JWT that identifies the logged in admin.
+func GenerateAdminJWT(u model.Admin) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":    u.ID.String(),
+		"email": u.Email,
+	})
+	key := viper.GetString("ACCESS_STRING")
+
+	return token.SignedString([]byte(key))
+}
+
+// Returns admin identified by "Token" header of the request.
+// Reports false if the request has no valid admin token.
+func requestAdmin(r *http.Request) (model.Admin, bool) {
+	var u model.Admin
+	if r.Header.Get("Token") == "" {
+		return u, false
+	}
+	key := viper.GetString("ACCESS_STRING")
+	token, err := jwt.Parse(r.Header.Get("Token"), func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(key), nil
+	})
+	if err != nil || !token.Valid {
+		return u, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return u, false
+	}
+	id, _ := claims["id"].(string)
+	u.ID, err = uuid.Parse(id)
+	if err != nil {
+		return u, false
+	}
+	u.Email, _ = claims["email"].(string)
+
+	return u, true
+}
@@ -5,12 +5,16 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	app "github.com/library/app/utils"
 	"github.com/library/db"
+	"github.com/library/images"
+	"github.com/library/model"
+	"github.com/library/storage"
 	"github.com/spf13/viper"
 )
 
@@ -46,6 +50,84 @@ func (a *App) Initialize() {
 	// Receives database credentials and connects to database.
 	d.Initialize(db_user, db_pass, db_host, db_name)
 
+	// Connect to image storage backend.
+	store, err = storage.New(viper.GetString("IMAGE_STORAGE"))
+	if err != nil {
+		log.Fatalf("Error while opening image storage %s", err)
+	}
+
+	// Largest image that is decoded for renditions.
+	if viper.IsSet("IMAGE_MAX_PIXELS") {
+		images.MaxPixels = viper.GetInt("IMAGE_MAX_PIXELS")
+	}
+
+	// Days a reader has to pick up a reserved copy.
+	if days := viper.GetInt("HOLD_PICKUP_DAYS"); days > 0 {
+		model.HoldPickupPeriod = time.Duration(days) * 24 * time.Hour
+	}
+
+	// Lending policy used when no stored policy matches a loan.
+	if viper.IsSet("POLICY_LOAN_PERIOD_DAYS") {
+		model.DefaultPolicy.LoanPeriod = viper.GetInt("POLICY_LOAN_PERIOD_DAYS")
+	}
+	if viper.IsSet("POLICY_MAX_LOANS") {
+		model.DefaultPolicy.MaxLoans = viper.GetInt("POLICY_MAX_LOANS")
+	}
+	if viper.IsSet("POLICY_MAX_RENEWALS") {
+		model.DefaultPolicy.MaxRenewals = viper.GetInt("POLICY_MAX_RENEWALS")
+	}
+	if viper.IsSet("POLICY_PRICE_MULTIPLIER") {
+		model.DefaultPolicy.PriceMultiplier = float32(viper.GetFloat64("POLICY_PRICE_MULTIPLIER"))
+	}
+	if viper.IsSet("POLICY_GRACE_DAYS") {
+		model.DefaultPolicy.GraceDays = viper.GetInt("POLICY_GRACE_DAYS")
+	}
+	if viper.IsSet("RENEWAL_OVERDUE_LIMIT_DAYS") {
+		model.RenewalOverdueLimit = viper.GetInt("RENEWAL_OVERDUE_LIMIT_DAYS")
+	}
+	// Return costs.
+	if viper.IsSet("LATE_FEE_PER_DAY") {
+		model.LateFeePerDay = float32(viper.GetFloat64("LATE_FEE_PER_DAY"))
+	}
+	if viper.IsSet("CONDITION_SURCHARGES") {
+		surcharges := map[string]float32{}
+		for condition := range viper.GetStringMap("CONDITION_SURCHARGES") {
+			surcharges[condition] = float32(viper.GetFloat64("CONDITION_SURCHARGES." + condition))
+		}
+		model.ConditionSurcharges = surcharges
+	}
+	if viper.IsSet("DAMAGE_SURCHARGES") {
+		surcharges := map[string]float32{}
+		for damage := range viper.GetStringMap("DAMAGE_SURCHARGES") {
+			surcharges[damage] = float32(viper.GetFloat64("DAMAGE_SURCHARGES." + damage))
+		}
+		model.DamageSurcharges = surcharges
+	}
+	if viper.IsSet("LOST_PROCESSING_FEE") {
+		model.LostProcessingFee = float32(viper.GetFloat64("LOST_PROCESSING_FEE"))
+	}
+	if viper.IsSet("MAX_DEBT") {
+		model.MaxDebt = float32(viper.GetFloat64("MAX_DEBT"))
+	}
+	if viper.IsSet("MAX_DISCOUNT_PERCENT") {
+		model.MaxDiscountPercent = float32(viper.GetFloat64("MAX_DISCOUNT_PERCENT"))
+	}
+	if viper.IsSet("READING_ROOM_CLOSING_TIME") {
+		model.ReadingRoomClosingTime = viper.GetString("READING_ROOM_CLOSING_TIME")
+	}
+
+	// Admins listed in config are privileged from the start, so the first
+	// one can grant the role to others.
+	if emails := viper.GetStringSlice("PRIVILEGED_ADMINS"); len(emails) > 0 {
+		unknown, err := model.GrantPrivileged(d.Database, emails)
+		if err != nil {
+			log.Fatalf("Error while granting privileged admins %s", err)
+		}
+		for _, email := range unknown {
+			log.Printf("No admin with email %s to make privileged", email)
+		}
+	}
+
 	a.Router = mux.NewRouter()
 	a.Router.HandleFunc("/Library", homePage)
 	a.AdminInitialize()
@@ -56,6 +138,15 @@ func (a *App) Initialize() {
 	a.IssueInitialize()
 	a.AcceptanceInitialize()
 	a.BooksInitialize()
+	a.VersionInitialize()
+	a.ReservationInitialize()
+	a.PolicyInitialize()
+	a.DiscountInitialize()
+	a.LedgerInitialize()
+	a.PaymentInitialize()
+	a.BranchInitialize()
+	a.LoanReceiptInitialize()
+	a.CalendarInitialize()
 }
 
 // Serve homepage
@@ -68,6 +159,11 @@ func homePage(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "ENV: %s", current_env)
 }
 
+// Returns the database the application is connected to.
+func (a *App) DB() db.DB {
+	return d
+}
+
 // Starts the application.
 func (a *App) Run(addr string) {
 	log.Printf("Server listening on port: %s", addr)
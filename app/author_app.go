@@ -1,6 +1,7 @@
 package app
 
 import (
+	"database/sql"
 	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -26,6 +27,8 @@ func (a *App) initializeAuthorRoutes() {
 	a.Router.HandleFunc("/author", a.createAuthor).Methods("POST")
 	a.Router.HandleFunc("/authors", a.getAuthors).Methods("GET")
 	a.Router.HandleFunc("/author/{id}", a.updateAuthor).Methods("PUT")
+	a.Router.HandleFunc("/author/{id}", a.deleteAuthor).Methods("DELETE")
+	a.Router.HandleFunc("/author/{id}/restore", a.restoreAuthor).Methods("POST")
 	a.Router.HandleFunc("/post/image", a.PostImage).Methods("POST")
 	a.Router.HandleFunc("/load/image", a.LoadImage).Methods("GET")
 
@@ -105,11 +108,70 @@ func (a *App) updateAuthor(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	dt.ID = id
 
-	if err := dt.UpdateAuthor(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	admin, _ := requestAdmin(r)
+	if err := dt.UpdateAuthor(d.Database, admin.Email); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if author not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Author not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with updated author.
 	app.RespondWithJSON(w, http.StatusOK, dt)
 }
 
+// Deletes author in db using id from URL.
+func (a *App) deleteAuthor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// Convert id string variable to int.
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	dt := model.Author{ID: id}
+	if err := dt.DeleteAuthor(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if author not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Author not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Restores soft deleted author in db using id from URL.
+func (a *App) restoreAuthor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// Convert id string variable to int.
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	dt := model.Author{ID: id}
+	if err := dt.RestoreAuthor(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if there is no deleted author with the id.
+			app.RespondWithError(w, http.StatusNotFound, "Deleted author not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
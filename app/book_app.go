@@ -29,9 +29,12 @@ func (a *App) initializeBookRoutes() {
 
 	a.Router.HandleFunc("/book", a.createBook).Methods("POST")
 	a.Router.HandleFunc("/books", a.getBooks).Methods("GET")
+	a.Router.HandleFunc("/books/usage", a.getBookUsage).Methods("GET")
 	a.Router.HandleFunc("/book/{name}", a.getBook).Methods("GET")
 	a.Router.HandleFunc("/book/{id}", a.updateBook).Methods("PUT")
 	a.Router.HandleFunc("/book/{id}", a.deleteBook).Methods("DELETE")
+	a.Router.HandleFunc("/book/{id}/restore", a.restoreBook).Methods("POST")
+	a.Router.HandleFunc("/book/{id}/damage-history", a.getDamageHistory).Methods("GET")
 	a.Router.HandleFunc("/post/image", a.PostImage).Methods("POST")
 	a.Router.HandleFunc("/load/image", a.LoadImage).Methods("GET")
 	a.Router.HandleFunc("/book/author", a.createBookToAuthor).Methods("POST")
@@ -110,7 +113,13 @@ func (a *App) createBook(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	if err := dt.CreateBook(d.Database, categoryId, authorId, booksNumber); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case model.ErrBranchNotFound:
+			// Respond with 404 if there is no branch for the copies.
+			app.RespondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with newly created book.
@@ -137,8 +146,16 @@ func (a *App) updateBook(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	dt.ID = id
 
-	if err := dt.UpdateBook(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	admin, _ := requestAdmin(r)
+	if err := dt.UpdateBook(d.Database, admin.Email); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if book not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Book not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with updated book.
@@ -156,7 +173,14 @@ func (a *App) deleteBook(w http.ResponseWriter, r *http.Request) {
 
 	dt := model.Book{ID: id}
 	if err := dt.DeleteBook(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if book not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Book not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with success message if operation is completed.
@@ -164,6 +188,74 @@ func (a *App) deleteBook(w http.ResponseWriter, r *http.Request) {
 }
 
 
+// Restores soft deleted book in db using id from URL.
+func (a *App) restoreBook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// Convert id string variable to int.
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	dt := model.Book{ID: id}
+	if err := dt.RestoreBook(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if there is no deleted book with the id.
+			app.RespondWithError(w, http.StatusNotFound, "Deleted book not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Gets condition assessments of book from URL from all its returns.
+func (a *App) getDamageHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	history, err := model.GetDamageHistory(d.Database, id)
+	if err != nil {
+		switch err {
+		case model.ErrBookNotFound:
+			// Respond with 404 if book not found in db.
+			app.RespondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, history)
+}
+
+// Gets how often books were lent in the period in from and to query
+// variables, home and reading-room loans counted apart.
+func (a *App) getBookUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := model.GetBookUsage(d.Database, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		switch err {
+		case model.ErrInvalidPeriod:
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, usage)
+}
+
+
 // Inserts new category into db.
 func (a *App) createBookToAuthor(w http.ResponseWriter, r *http.Request) {
 	var dt model.BookToAuthors
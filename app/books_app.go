@@ -106,6 +106,10 @@ func (a *App) createNumberBook(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	if err := dt.CreateNumberBook(d.Database); err != nil {
+		if err == model.ErrBranchNotFound {
+			app.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -133,8 +137,16 @@ func (a *App) updateNumberBook(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	dt.ID = id
 
-	if err := dt.UpdateNumberBook(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	admin, _ := requestAdmin(r)
+	if err := dt.UpdateNumberBook(d.Database, admin.Email); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if book not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Book not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with updated book.
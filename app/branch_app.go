@@ -0,0 +1,256 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) BranchInitialize() {
+	a.initializeBranchRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeBranchRoutes() {
+	a.Router.HandleFunc("/branches", a.getBranches).Methods("GET")
+	a.Router.HandleFunc("/branch", a.createBranch).Methods("POST")
+	a.Router.HandleFunc("/branch/{id}", a.getBranch).Methods("GET")
+	a.Router.HandleFunc("/branch/{id}", a.updateBranch).Methods("PUT")
+	a.Router.HandleFunc("/branch/{id}", a.deleteBranch).Methods("DELETE")
+	a.Router.HandleFunc("/book/{id}/stock", a.getBookStock).Methods("GET")
+	a.Router.HandleFunc("/transfers", a.getTransfers).Methods("GET")
+	a.Router.HandleFunc("/transfer", a.createTransfer).Methods("POST")
+	a.Router.HandleFunc("/transfer/{id}", a.getTransfer).Methods("GET")
+	a.Router.HandleFunc("/transfer/{id}/ship", a.shipTransfer).Methods("POST")
+	a.Router.HandleFunc("/transfer/{id}/receive", a.receiveTransfer).Methods("POST")
+	a.Router.HandleFunc("/transfer/{id}/cancel", a.cancelTransfer).Methods("POST")
+}
+
+// Route handlers
+
+// Retrieves branch from db using id from URL.
+func (a *App) getBranch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid branch ID")
+		return
+	}
+
+	dt := model.Branch{ID: id}
+	if err := dt.GetBranch(d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	// If data found respond with branch object.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets list of branches.
+func (a *App) getBranches(w http.ResponseWriter, r *http.Request) {
+	branches, err := model.GetBranches(d.Database)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, branches)
+}
+
+// Inserts new branch into db.
+func (a *App) createBranch(w http.ResponseWriter, r *http.Request) {
+	var dt model.Branch
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreateBranch(d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	// Respond with newly created branch.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Updates branch in db using id from URL.
+func (a *App) updateBranch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid branch ID")
+		return
+	}
+
+	var dt model.Branch
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+	dt.ID = id
+
+	if err := dt.UpdateBranch(d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	// Respond with updated branch.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Deletes branch in db using id from URL.
+func (a *App) deleteBranch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid branch ID")
+		return
+	}
+
+	dt := model.Branch{ID: id}
+	if err := dt.DeleteBranch(d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Gets copies of book from URL per branch.
+func (a *App) getBookStock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	stock, err := model.GetBookStock(d.Database, id)
+	if err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, stock)
+}
+
+// Retrieves transfer from db using id from URL.
+func (a *App) getTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid transfer ID")
+		return
+	}
+
+	dt := model.Transfer{ID: id}
+	if err := dt.GetTransfer(d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets list of transfers filtered by status and branch from URL query.
+func (a *App) getTransfers(w http.ResponseWriter, r *http.Request) {
+	var branchID *uuid.UUID
+	if value := r.URL.Query().Get("branch"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid branch ID")
+			return
+		}
+		branchID = &id
+	}
+
+	transfers, err := model.GetTransfers(d.Database, r.URL.Query().Get("status"), branchID)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, transfers)
+}
+
+// Requests new transfer of copies between branches.
+func (a *App) createTransfer(w http.ResponseWriter, r *http.Request) {
+	var dt model.Transfer
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreateTransfer(d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	// Respond with newly requested transfer.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Ships transfer from URL.
+func (a *App) shipTransfer(w http.ResponseWriter, r *http.Request) {
+	a.changeTransfer(w, r, (*model.Transfer).ShipTransfer)
+}
+
+// Receives transfer from URL at its destination.
+func (a *App) receiveTransfer(w http.ResponseWriter, r *http.Request) {
+	a.changeTransfer(w, r, (*model.Transfer).ReceiveTransfer)
+}
+
+// Cancels transfer from URL.
+func (a *App) cancelTransfer(w http.ResponseWriter, r *http.Request) {
+	a.changeTransfer(w, r, (*model.Transfer).CancelTransfer)
+}
+
+// Applies a status change to transfer from URL and responds with it.
+func (a *App) changeTransfer(w http.ResponseWriter, r *http.Request, change func(*model.Transfer, *sql.DB) error) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid transfer ID")
+		return
+	}
+
+	dt := model.Transfer{ID: id}
+	if err := change(&dt, d.Database); err != nil {
+		respondWithBranchError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Responds with the status matching a branch or transfer error.
+func respondWithBranchError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		app.RespondWithError(w, http.StatusNotFound, "not found")
+	case model.ErrBranchNotFound, model.ErrBookNotFound:
+		app.RespondWithError(w, http.StatusNotFound, err.Error())
+	case model.ErrSameBranch:
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case model.ErrBranchNotEmpty, model.ErrTransferStatus, model.ErrNoCopiesAvailable:
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) CalendarInitialize() {
+	a.initializeCalendarRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeCalendarRoutes() {
+	a.Router.HandleFunc("/opening-hours", a.getOpeningHours).Methods("GET")
+	a.Router.HandleFunc("/opening-hours", a.setOpeningHours).Methods("PUT")
+	a.Router.HandleFunc("/holidays", a.getHolidays).Methods("GET")
+	a.Router.HandleFunc("/holiday", a.createHoliday).Methods("POST")
+	a.Router.HandleFunc("/holiday/{id}", a.deleteHoliday).Methods("DELETE")
+}
+
+// Route handlers
+
+// Gets opening hours of the branch in URL query, or of the library.
+func (a *App) getOpeningHours(w http.ResponseWriter, r *http.Request) {
+	branchID, ok := branchQuery(w, r)
+	if !ok {
+		return
+	}
+
+	hours, err := model.GetOpeningHours(d.Database, branchID)
+	if err != nil {
+		respondWithCalendarError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, hours)
+}
+
+// Replaces opening hours of the branch in URL query, or of the library.
+func (a *App) setOpeningHours(w http.ResponseWriter, r *http.Request) {
+	branchID, ok := branchQuery(w, r)
+	if !ok {
+		return
+	}
+	var hours []model.OpeningHours
+	// Gets JSON array from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&hours); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := model.SetOpeningHours(d.Database, branchID, hours); err != nil {
+		respondWithCalendarError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, hours)
+}
+
+// Gets holidays of the branch in URL query, or of every branch, for the
+// period in from and to query variables.
+func (a *App) getHolidays(w http.ResponseWriter, r *http.Request) {
+	branchID, ok := branchQuery(w, r)
+	if !ok {
+		return
+	}
+
+	holidays, err := model.GetHolidays(d.Database, branchID, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		respondWithCalendarError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, holidays)
+}
+
+// Inserts new holiday into db.
+func (a *App) createHoliday(w http.ResponseWriter, r *http.Request) {
+	var dt model.Holiday
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreateHoliday(d.Database); err != nil {
+		respondWithCalendarError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Deletes holiday from db using id from URL.
+func (a *App) deleteHoliday(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid holiday ID")
+		return
+	}
+
+	dt := model.Holiday{ID: id}
+	if err := dt.DeleteHoliday(d.Database); err != nil {
+		respondWithCalendarError(w, err)
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Gets branch from URL query. Responds with the error and returns false if
+// it is not a valid id.
+func branchQuery(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	value := r.URL.Query().Get("branch")
+	if value == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid branch ID")
+		return nil, false
+	}
+	return &id, true
+}
+
+// Responds with the status matching a calendar error.
+func respondWithCalendarError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		// Respond with 404 if holiday not found in db.
+		app.RespondWithError(w, http.StatusNotFound, "Holiday not found")
+	case model.ErrBranchNotFound:
+		app.RespondWithError(w, http.StatusNotFound, err.Error())
+	case model.ErrHolidayExists:
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	case model.ErrInvalidHours, model.ErrInvalidHoliday, model.ErrHolidayName, model.ErrInvalidPeriod:
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) DiscountInitialize() {
+	a.initializeDiscountRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeDiscountRoutes() {
+	a.Router.HandleFunc("/discount-rules", a.getDiscountRules).Methods("GET")
+	a.Router.HandleFunc("/discount-rule", a.createDiscountRule).Methods("POST")
+	a.Router.HandleFunc("/discount-rule/{id}", a.getDiscountRule).Methods("GET")
+	a.Router.HandleFunc("/discount-rule/{id}", a.updateDiscountRule).Methods("PUT")
+	a.Router.HandleFunc("/discount-rule/{id}", a.deleteDiscountRule).Methods("DELETE")
+}
+
+// Route handlers
+
+// Retrieves discount rule from db using id from URL.
+func (a *App) getDiscountRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid discount rule ID")
+		return
+	}
+
+	dt := model.DiscountRule{ID: id}
+	if err := dt.GetDiscountRule(d.Database); err != nil {
+		respondWithDiscountError(w, err)
+		return
+	}
+	// If data found respond with discount rule object.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets list of discount rules, only active ones if ?active=true.
+func (a *App) getDiscountRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := model.GetDiscountRules(d.Database, r.URL.Query().Get("active") == "true")
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, rules)
+}
+
+// Inserts new discount rule into db. Rules are active unless the payload
+// says otherwise.
+func (a *App) createDiscountRule(w http.ResponseWriter, r *http.Request) {
+	var dt model.DiscountRule
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreateDiscountRule(d.Database); err != nil {
+		respondWithDiscountError(w, err)
+		return
+	}
+	// Respond with newly created discount rule.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Updates discount rule in db using id from URL.
+func (a *App) updateDiscountRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid discount rule ID")
+		return
+	}
+
+	var dt model.DiscountRule
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+	dt.ID = id
+
+	if err := dt.UpdateDiscountRule(d.Database); err != nil {
+		respondWithDiscountError(w, err)
+		return
+	}
+	// Respond with updated discount rule.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Deletes discount rule in db using id from URL.
+func (a *App) deleteDiscountRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid discount rule ID")
+		return
+	}
+
+	dt := model.DiscountRule{ID: id}
+	if err := dt.DeleteDiscountRule(d.Database); err != nil {
+		respondWithDiscountError(w, err)
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Responds with the status matching a discount rule error.
+func respondWithDiscountError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		// Respond with 404 if discount rule not found in db.
+		app.RespondWithError(w, http.StatusNotFound, "discount rule not found")
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
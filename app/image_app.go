@@ -1,17 +1,44 @@
 package app
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"github.com/spf13/viper"
+	"github.com/google/uuid"
+	app "github.com/library/app/utils"
+	"github.com/library/images"
+	"github.com/library/model"
+	"github.com/library/storage"
+	"image"
 	"io"
 	"io/ioutil"
+	"log"
+	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"time"
 )
 const MAX_UPLOAD_SIZE = 1024 * 1024
 
+// File extensions of accepted image types.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// Storage backend for uploaded images.
+var store storage.Storage
+
+// Uploaded image with links to its renditions.
+type uploadedImage struct {
+	model.Image
+	URLs map[string]string `json:"urls"`
+}
+
+// Stores uploaded images and responds with their IDs. Books, authors and
+// acceptances refer to an image by putting its ID into "photo".
 func (a *App) PostImage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -25,6 +52,7 @@ func (a *App) PostImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	files := r.MultipartForm.File["file"]
+	uploaded := []uploadedImage{}
 
 	for _, fileHeader := range files {
 		if fileHeader.Size > MAX_UPLOAD_SIZE {
@@ -59,40 +87,200 @@ func (a *App) PostImage(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		path := viper.GetString("IMAGE_POST_PATH")
-		err = os.MkdirAll(path, os.ModePerm)
+		content, err := ioutil.ReadAll(file)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		f, err := os.Create(fmt.Sprintf("%s/%d%s", path, time.Now().UnixNano(), filepath.Ext(fileHeader.Filename)))
+		config, _, err := image.DecodeConfig(bytes.NewReader(content))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		// Only the header is read so far; refuse sizes too large to decode.
+		if err := images.CheckPixels(config.Width, config.Height); err != nil {
+			http.Error(w, fmt.Sprintf("The uploaded image is too large: %s is %dx%d pixels", fileHeader.Filename, config.Width, config.Height), http.StatusBadRequest)
+			return
+		}
 
-		defer f.Close()
-
-		_, err = io.Copy(f, file)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		// Files are named after the content hash, so identical uploads share them.
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		dt := model.Image{
+			Filename:     hash + imageExtensions[filetype],
+			OriginalName: fileHeader.Filename,
+			ContentType:  filetype,
+			Size:         int64(len(content)),
+			Width:        config.Width,
+			Height:       config.Height,
+			SHA256:       hash,
+		}
+		if err := dt.CreateImage(d.Database, func() error {
+			return storeImage(dt.Filename, content, filetype)
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		uploaded = append(uploaded, uploadedImage{Image: dt, URLs: imageURLs(dt.ID)})
 	}
 
-	fmt.Fprintf(w, "Upload successful")
+	// Respond with stored images.
+	app.RespondWithJSON(w, http.StatusCreated, uploaded)
 }
 
+// Serves an uploaded image. The "image" parameter is an image ID or, for
+// images uploaded before IDs existed, a file name. The "size" parameter
+// selects the rendition: thumbnail, medium or original (default).
+// Conditional and Range requests are handled by http.ServeContent.
 func (a *App) LoadImage(w http.ResponseWriter, r *http.Request) {
-	path := viper.GetString("IMAGE_LOAD_PATH")
-	image := r.URL.Query().Get("image")
-	filename := fmt.Sprintf(  "%s/%s", path, image)
-	file, err := ioutil.ReadFile(filename)
-	if err != nil{
+	filename := r.URL.Query().Get("image")
+	size := r.URL.Query().Get("size")
+	contentType := mime.TypeByExtension(path.Ext(filename))
+	hash := ""
+	if id, err := uuid.Parse(filename); err == nil {
+		dt := model.Image{ID: id}
+		if err := dt.GetImage(d.Database); err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				http.Error(w, "Image not found", http.StatusNotFound)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		filename, contentType, hash = dt.Filename, dt.ContentType, dt.SHA256
+	}
+	name, err := images.RenditionName(filename, size)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
-	}else {
-		w.Write(file)
+		return
+	}
+	file, err := store.Get(name)
+	if err == storage.ErrNotExist && name != filename {
+		// Images uploaded before renditions existed only have the original.
+		name, size = filename, images.Original
+		file, err = store.Get(name)
+	}
+	if err != nil {
+		switch err {
+		case storage.ErrNotExist:
+			// Names outside the storage root are reported the same way.
+			http.Error(w, "Image not found", http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	content, ok := file.ReadCloser.(io.ReadSeeker)
+	if !ok {
+		// Remote objects can't seek; images are small enough to buffer.
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if hash != "" {
+		// Content never changes for a hash, so the ETag is strong.
+		w.Header().Set("ETag", imageETag(hash, size))
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, file.ModTime.Unix(), file.Size))
+		w.Header().Set("Cache-Control", "public, no-cache")
+	}
+	http.ServeContent(w, r, name, file.ModTime, content)
+}
+
+// Stores original upload and its renditions. Content that can't be
+// decoded is not stored, and files already stored are removed if a
+// rendition fails.
+func storeImage(filename string, content []byte, contentType string) error {
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if err := store.Put(filename, bytes.NewReader(content), int64(len(content)), contentType); err != nil {
+		return err
+	}
+	for _, rendition := range images.Renditions {
+		name, _ := images.RenditionName(filename, rendition.Name)
+		if err := saveRendition(name, images.Resize(img, rendition.MaxSize), contentType); err != nil {
+			if cleanupErr := deleteImageFiles(filename); cleanupErr != nil {
+				log.Printf("Can not remove files of image %s: %s", filename, cleanupErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns strong ETag of an image rendition.
+func imageETag(hash, size string) string {
+	if size == "" || size == images.Original {
+		return `"` + hash + `"`
+	}
+	return `"` + hash + "-" + size + `"`
+}
+
+// Encodes resized image and stores it under name.
+func saveRendition(name string, img image.Image, contentType string) error {
+	var buf bytes.Buffer
+	if err := images.Encode(&buf, img, contentType); err != nil {
+		return err
+	}
+	return store.Put(name, &buf, int64(buf.Len()), contentType)
+}
+
+// Returns links to every rendition of an image.
+func imageURLs(id uuid.UUID) map[string]string {
+	urls := map[string]string{
+		images.Original: "/load/image?image=" + id.String(),
+	}
+	for _, rendition := range images.Renditions {
+		urls[rendition.Name] = "/load/image?image=" + id.String() + "&size=" + rendition.Name
+	}
+	return urls
+}
+
+// Removes images that are older than the given time and not referenced
+// by any book, author or acceptance. Returns the number of removed images.
+func (a *App) CleanupImages(before time.Time) (int, error) {
+	unreferenced, err := model.GetUnreferencedImages(d.Database, before)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, dt := range unreferenced {
+		filename := dt.Filename
+		if err := dt.DeleteImage(d.Database, func() error {
+			return deleteImageFiles(filename)
+		}); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Removes original file and renditions of an image.
+func deleteImageFiles(filename string) error {
+	names := []string{filename}
+	for _, rendition := range images.Renditions {
+		name, _ := images.RenditionName(filename, rendition.Name)
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if err := store.Delete(name); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -29,9 +30,18 @@ func (a *App) initializeIssueRoutes() {
 
 	a.Router.HandleFunc("/issue", a.createIssue).Methods("POST")
 	a.Router.HandleFunc("/issuing", a.getIssuing).Methods("GET")
+	a.Router.HandleFunc("/issuing/overdue", a.getOverdue).Methods("GET")
+	a.Router.HandleFunc("/issuing/flagged", a.getFlagged).Methods("GET")
 	a.Router.HandleFunc("/issue/{id}", a.getIssue).Methods("GET")
 	a.Router.HandleFunc("/issue/{id}", a.updateIssue).Methods("PUT")
 	a.Router.HandleFunc("/issue/{id}", a.deleteIssue).Methods("DELETE")
+	a.Router.HandleFunc("/issue/{id}/restore", a.restoreIssue).Methods("POST")
+	a.Router.HandleFunc("/issue/{id}/renew", a.renewIssue).Methods("POST")
+	a.Router.HandleFunc("/issue/{id}/return", a.returnIssue).Methods("POST")
+	a.Router.HandleFunc("/issue/{id}/lost", a.declareLost).Methods("POST")
+	a.Router.HandleFunc("/issue/{id}/found", a.reverseLost).Methods("POST")
+	a.Router.HandleFunc("/user/{id}/lending-block", a.getLendingBlock).Methods("GET")
+	a.Router.HandleFunc("/lending-overrides", a.getLendingOverrides).Methods("GET")
 }
 
 // Route handlers
@@ -92,6 +102,41 @@ func (a *App) getIssuing(w http.ResponseWriter, r *http.Request) {
 	app.RespondWithJSON(w, http.StatusOK, issue)
 }
 
+// Gets overdue loans with the fines accrued so far. Query takes field and
+// sort to order them, and format=csv to download them as a spreadsheet.
+func (a *App) getOverdue(w http.ResponseWriter, r *http.Request) {
+	loans, err := model.GetOverdueLoans(d.Database, r.URL.Query().Get("field"), r.URL.Query().Get("sort"))
+	if err != nil {
+		if _, ok := err.(*model.InvalidSortError); ok {
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		app.RespondWithJSON(w, http.StatusOK, loans)
+		return
+	}
+	records := [][]string{{"issue_id", "firstname", "surname", "email", "title", "return_date", "days_overdue", "late_days", "fine"}}
+	for _, v := range loans {
+		records = append(records, []string{v.IssueID.String(), v.Firstname, v.Surname, v.Email, v.Title, v.ReturnDate,
+			strconv.Itoa(v.DaysOverdue), strconv.Itoa(v.LateDays), strconv.FormatFloat(float64(v.Fine), 'f', 2, 32)})
+	}
+	app.RespondWithCSV(w, "overdue-"+time.Now().Format(model.DateLayout)+".csv", records)
+}
+
+// Gets reading-room loans still open after closing time.
+func (a *App) getFlagged(w http.ResponseWriter, r *http.Request) {
+	loans, err := model.GetFlaggedLoans(d.Database)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, loans)
+}
+
 // Inserts new issue into db.
 func (a *App) createIssue(w http.ResponseWriter, r *http.Request) {
 	var dt model.Issue
@@ -104,8 +149,12 @@ func (a *App) createIssue(w http.ResponseWriter, r *http.Request) {
 
 	defer r.Body.Close()
 
+	if !overrideAdmin(w, r, &dt) {
+		return
+	}
+
 	if err := dt.CreateIssue(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithIssueError(w, err)
 		return
 	}
 	// Respond with newly created issue.
@@ -132,8 +181,12 @@ func (a *App) updateIssue(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	dt.ID = id
 
+	if !overrideAdmin(w, r, &dt) {
+		return
+	}
+
 	if err := dt.UpdateIssue(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithIssueError(w, err)
 		return
 	}
 	// Respond with updated issue.
@@ -151,9 +204,244 @@ func (a *App) deleteIssue(w http.ResponseWriter, r *http.Request) {
 
 	dt := model.Issue{ID: id}
 	if err := dt.DeleteIssue(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if issue not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "Issue not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Restores soft deleted issue in db using id from URL.
+func (a *App) restoreIssue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// Convert id string variable to int.
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid issue ID")
+		return
+	}
+
+	dt := model.Issue{ID: id}
+	if err := dt.RestoreIssue(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if there is no deleted issue with the id.
+			app.RespondWithError(w, http.StatusNotFound, "Deleted issue not found")
+		default:
+			respondWithIssueError(w, err)
+		}
 		return
 	}
 	// Respond with success message if operation is completed.
 	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
-}
\ No newline at end of file
+}
+
+// Extends loan using id from URL by the renewal period.
+func (a *App) renewIssue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid issue ID")
+		return
+	}
+
+	dt := model.Issue{ID: id}
+	if err := dt.RenewIssue(d.Database); err != nil {
+		respondWithIssueError(w, err)
+		return
+	}
+	// Respond with renewed issue.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Accepts returned book of loan using id from URL. Creates acceptance
+// with the final cost and closes the loan.
+func (a *App) returnIssue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid issue ID")
+		return
+	}
+
+	var dt model.Acceptance
+	// Gets JSON object with book condition, damages and photos from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.ReturnIssue(d.Database, id); err != nil {
+		switch err.(type) {
+		case *model.UnknownConditionError, *model.UnknownDamageError:
+			app.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithIssueError(w, err)
+		return
+	}
+	// Respond with newly created acceptance.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Sets the admin of a lending block override to the admin making the
+// request, never one named in the payload. Responds with 401 and reports
+// false if an override comes without an admin.
+func overrideAdmin(w http.ResponseWriter, r *http.Request, dt *model.Issue) bool {
+	if dt.Override == nil {
+		return true
+	}
+	u, ok := requestAdmin(r)
+	if !ok {
+		app.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	dt.Override.AdminID = u.ID
+	return true
+}
+
+// Responds with the status matching an issuing error.
+func respondWithIssueError(w http.ResponseWriter, err error) {
+	if refusal, ok := err.(*model.PolicyError); ok {
+		// Respond with 422 and every violated rule if lending policy refuses the loan.
+		app.RespondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":      refusal.Error(),
+			"policy":     refusal.Policy,
+			"violations": refusal.Violations,
+		})
+		return
+	}
+	if block, ok := err.(*model.LendingBlockError); ok {
+		// Respond with 403 and every reason if the reader is blocked from borrowing.
+		app.RespondWithJSON(w, http.StatusForbidden, map[string]interface{}{
+			"error":         block.Error(),
+			"reasons":       block.Reasons,
+			"overdueIssues": block.OverdueIssues,
+			"balance":       block.Balance,
+			"debtLimit":     block.DebtLimit,
+		})
+		return
+	}
+	switch err {
+	case sql.ErrNoRows:
+		// Respond with 404 if issue not found in db.
+		app.RespondWithError(w, http.StatusNotFound, "issue not found")
+	case model.ErrNotPrivileged:
+		app.RespondWithError(w, http.StatusForbidden, err.Error())
+	case model.ErrOverrideReason:
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case model.ErrUserNotFound, model.ErrBookNotFound, model.ErrBranchNotFound:
+		app.RespondWithError(w, http.StatusNotFound, err.Error())
+	case model.ErrNoCopiesAvailable:
+		// Respond with 409 if every copy is already lent out.
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	case model.ErrHoldAtOtherBranch:
+		// Respond with 409 if the reader's copy waits at another branch.
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	case model.ErrLoanClosed, model.ErrRenewalLimit, model.ErrLoanOverdue, model.ErrBookOnHold:
+		// Respond with 409 if lending policy refuses the renewal.
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	case model.ErrNotLost, model.ErrAlreadyFound:
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	case model.ErrReadingRoomOnly, model.ErrReadingRoomReturn:
+		// Respond with 409 if the book can't leave the reading room.
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	case model.ErrLoanType:
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case model.ErrClientCost:
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case model.ErrInvalidReturn, model.ErrReturnBefore:
+		app.RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Declares book of issue from URL lost.
+func (a *App) declareLost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid issue ID")
+		return
+	}
+
+	dt := model.Issue{ID: id}
+	if err := dt.DeclareLost(d.Database); err != nil {
+		respondWithIssueError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Reverses lost declaration of issue from URL once the book turns up.
+func (a *App) reverseLost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid issue ID")
+		return
+	}
+
+	dt := model.Issue{ID: id}
+	if err := dt.ReverseLost(d.Database); err != nil {
+		respondWithIssueError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets why user from URL is blocked from borrowing. Responds with blocked
+// false if they may borrow.
+func (a *App) getLendingBlock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	block, err := model.GetLendingBlock(d.Database, id)
+	if err != nil {
+		respondWithIssueError(w, err)
+		return
+	}
+	if block == nil {
+		app.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"blocked": false})
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"blocked": true, "block": block})
+}
+
+// Gets recorded overrides of lending blocks, for one reader if userID is
+// in URL query.
+func (a *App) getLendingOverrides(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if value := r.URL.Query().Get("userID"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		userID = &id
+	}
+
+	overrides, err := model.GetLendingOverrides(d.Database, userID)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, overrides)
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) LedgerInitialize() {
+	a.initializeLedgerRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeLedgerRoutes() {
+	a.Router.HandleFunc("/user/{id}/statement", a.getStatement).Methods("GET")
+	a.Router.HandleFunc("/user/{id}/ledger", a.createLedgerEntry).Methods("POST")
+	a.Router.HandleFunc("/debtors", a.getDebtors).Methods("GET")
+}
+
+// Route handlers
+
+// Gets statement of user from URL for the period in from and to query
+// variables.
+func (a *App) getStatement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	st, err := model.GetStatement(d.Database, id, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		respondWithLedgerError(w, err)
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, st)
+}
+
+// Gets readers with a balance above min from URL query, 0 by default.
+func (a *App) getDebtors(w http.ResponseWriter, r *http.Request) {
+	var min float64
+	if value := r.URL.Query().Get("min"); value != "" {
+		var err error
+		if min, err = strconv.ParseFloat(value, 32); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid min balance")
+			return
+		}
+	}
+
+	debtors, err := model.GetDebtors(d.Database, float32(min))
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, debtors)
+}
+
+// Records a charge or credit on ledger of user from URL.
+func (a *App) createLedgerEntry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var dt model.LedgerEntry
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+	dt.UserID = id
+
+	if err := dt.CreateLedgerEntry(d.Database); err != nil {
+		respondWithLedgerError(w, err)
+		return
+	}
+	// Respond with newly created entry and the resulting balance.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Responds with the status matching a ledger error.
+func respondWithLedgerError(w http.ResponseWriter, err error) {
+	switch err {
+	case model.ErrUserNotFound:
+		app.RespondWithError(w, http.StatusNotFound, "User not found")
+	case model.ErrInvalidPeriod:
+		app.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
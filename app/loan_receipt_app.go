@@ -0,0 +1,128 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/mail"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) LoanReceiptInitialize() {
+	a.initializeLoanReceiptRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeLoanReceiptRoutes() {
+	a.Router.HandleFunc("/issue/{id}/receipt", a.getLoanReceipt).Methods("GET")
+	a.Router.HandleFunc("/issue/{id}/receipt/email", a.emailLoanReceipt).Methods("POST")
+	a.Router.HandleFunc("/acceptance/{id}/receipt", a.getReturnReceipt).Methods("GET")
+	a.Router.HandleFunc("/acceptance/{id}/receipt/email", a.emailReturnReceipt).Methods("POST")
+}
+
+// Route handlers
+
+// Downloads PDF receipt of issue from URL.
+func (a *App) getLoanReceipt(w http.ResponseWriter, r *http.Request) {
+	receipt, ok := loanReceipt(w, r)
+	if !ok {
+		return
+	}
+	app.RespondWithPDF(w, "loan-"+receipt.IssueID.String()+".pdf", receipt.PDF())
+}
+
+// Emails PDF receipt of issue from URL to the reader.
+func (a *App) emailLoanReceipt(w http.ResponseWriter, r *http.Request) {
+	receipt, ok := loanReceipt(w, r)
+	if !ok {
+		return
+	}
+	email := mail.NewEmail([]string{receipt.Email}, "Loan receipt",
+		"You borrowed \""+receipt.Title+"\". Please return it by "+receipt.ReturnDate+". Your receipt is attached.")
+	email.Attach("loan-"+receipt.IssueID.String()+".pdf", "application/pdf", receipt.PDF())
+	sendReceipt(w, email, receipt.Email)
+}
+
+// Downloads PDF receipt of acceptance from URL.
+func (a *App) getReturnReceipt(w http.ResponseWriter, r *http.Request) {
+	receipt, ok := returnReceipt(w, r)
+	if !ok {
+		return
+	}
+	app.RespondWithPDF(w, "return-"+receipt.AcceptanceID.String()+".pdf", receipt.PDF())
+}
+
+// Emails PDF receipt of acceptance from URL to the reader.
+func (a *App) emailReturnReceipt(w http.ResponseWriter, r *http.Request) {
+	receipt, ok := returnReceipt(w, r)
+	if !ok {
+		return
+	}
+	email := mail.NewEmail([]string{receipt.Email}, "Return receipt",
+		"You returned \""+receipt.Title+"\". Your receipt is attached.")
+	email.Attach("return-"+receipt.AcceptanceID.String()+".pdf", "application/pdf", receipt.PDF())
+	sendReceipt(w, email, receipt.Email)
+}
+
+// Gets receipt of issue from URL. Responds with the error and returns
+// false if there is none.
+func loanReceipt(w http.ResponseWriter, r *http.Request) (model.LoanReceipt, bool) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid issue ID")
+		return model.LoanReceipt{}, false
+	}
+
+	receipt, err := model.GetLoanReceipt(d.Database, id)
+	if err != nil {
+		respondWithReceiptError(w, err, "Issue not found")
+		return receipt, false
+	}
+	return receipt, true
+}
+
+// Gets receipt of acceptance from URL. Responds with the error and returns
+// false if there is none.
+func returnReceipt(w http.ResponseWriter, r *http.Request) (model.ReturnReceipt, bool) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid acceptance ID")
+		return model.ReturnReceipt{}, false
+	}
+
+	receipt, err := model.GetReturnReceipt(d.Database, id)
+	if err != nil {
+		respondWithReceiptError(w, err, "Acceptance not found")
+		return receipt, false
+	}
+	return receipt, true
+}
+
+// Responds with the status matching a receipt error.
+func respondWithReceiptError(w http.ResponseWriter, err error, notFound string) {
+	switch err {
+	case sql.ErrNoRows:
+		// Respond with 404 if there is nothing to make a receipt of.
+		app.RespondWithError(w, http.StatusNotFound, notFound)
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Sends receipt email to the reader.
+func sendReceipt(w http.ResponseWriter, email *mail.Email, to string) {
+	if err := mail.SendEmail(email); err != nil {
+		// Respond with 502 if the mail server refuses the email.
+		app.RespondWithError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success", "sentTo": to})
+}
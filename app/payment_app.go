@@ -0,0 +1,184 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) PaymentInitialize() {
+	a.initializePaymentRoutes()
+}
+
+// Defines routes.
+func (a *App) initializePaymentRoutes() {
+	a.Router.HandleFunc("/payment", a.createPayment).Methods("POST")
+	a.Router.HandleFunc("/payment/{id}", a.getPayment).Methods("GET")
+	a.Router.HandleFunc("/payment/{id}/settle", a.settlePayment).Methods("POST")
+	a.Router.HandleFunc("/payment/{id}/void", a.voidPayment).Methods("POST")
+	a.Router.HandleFunc("/payment/{id}/refund", a.refundPayment).Methods("POST")
+	a.Router.HandleFunc("/payment/{id}/receipt", a.getReceipt).Methods("GET")
+	a.Router.HandleFunc("/user/{id}/payments", a.getUserPayments).Methods("GET")
+}
+
+// Route handlers
+
+// Retrieves payment from db using id from URL.
+func (a *App) getPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	dt := model.Payment{ID: id}
+	if err := dt.GetPayment(d.Database); err != nil {
+		respondWithPaymentError(w, err)
+		return
+	}
+	// If data found respond with payment object.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets payments of user from URL.
+func (a *App) getUserPayments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	payments, err := model.GetUserPayments(d.Database, id)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, payments)
+}
+
+// Gets receipt of payment from URL.
+func (a *App) getReceipt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	receipt, err := model.GetReceipt(d.Database, id)
+	if err != nil {
+		respondWithPaymentError(w, err)
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, receipt)
+}
+
+// Records new payment.
+func (a *App) createPayment(w http.ResponseWriter, r *http.Request) {
+	var dt model.Payment
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreatePayment(d.Database); err != nil {
+		respondWithPaymentError(w, err)
+		return
+	}
+	// Respond with newly created payment.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Settles pending payment from URL.
+func (a *App) settlePayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	dt := model.Payment{ID: id}
+	if err := dt.SettlePayment(d.Database); err != nil {
+		respondWithPaymentError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Voids pending payment from URL.
+func (a *App) voidPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	dt := model.Payment{ID: id}
+	if err := dt.VoidPayment(d.Database); err != nil {
+		respondWithPaymentError(w, err)
+		return
+	}
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Refunds payment from URL. An empty body refunds the whole payment.
+func (a *App) refundPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	var dt model.Payment
+	if r.ContentLength != 0 {
+		// Gets JSON object from request body.
+		decoder := json.NewDecoder(r.Body)
+		if err := decoder.Decode(&dt); err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.RefundPayment(d.Database, id); err != nil {
+		respondWithPaymentError(w, err)
+		return
+	}
+	// Respond with the refund.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Responds with the status matching a payment error.
+func respondWithPaymentError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		// Respond with 404 if payment not found in db.
+		app.RespondWithError(w, http.StatusNotFound, "payment not found")
+	case model.ErrUserNotFound, model.ErrAcceptanceNotFound:
+		app.RespondWithError(w, http.StatusNotFound, err.Error())
+	case model.ErrOverpayment, model.ErrOverRefund, model.ErrNotRefundable, model.ErrPaymentNotPending, model.ErrNoReceipt:
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+func (a *App) PolicyInitialize() {
+	a.initializePolicyRoutes()
+}
+
+// Defines routes.
+func (a *App) initializePolicyRoutes() {
+	a.Router.HandleFunc("/policies", a.getPolicies).Methods("GET")
+	a.Router.HandleFunc("/policy", a.createPolicy).Methods("POST")
+	a.Router.HandleFunc("/policy/evaluate", a.evaluatePolicy).Methods("GET")
+	a.Router.HandleFunc("/policy/{id}", a.getPolicy).Methods("GET")
+	a.Router.HandleFunc("/policy/{id}", a.updatePolicy).Methods("PUT")
+	a.Router.HandleFunc("/policy/{id}", a.deletePolicy).Methods("DELETE")
+}
+
+// Route handlers
+
+// Retrieves policy from db using id from URL.
+func (a *App) getPolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	dt := model.LendingPolicy{ID: id}
+	if err := dt.GetPolicy(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if policy not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "policy not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// If data found respond with policy object.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets list of stored policies.
+func (a *App) getPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := model.GetPolicies(d.Database)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, policies)
+}
+
+// Gets the policy applying to a loan of bookID by userID from URL query.
+func (a *App) evaluatePolicy(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.URL.Query().Get("userID"))
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	bookID, err := uuid.Parse(r.URL.Query().Get("bookID"))
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	policy, err := model.GetLoanPolicy(d.Database, userID, bookID)
+	if err != nil {
+		switch err {
+		case model.ErrUserNotFound:
+			app.RespondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, policy)
+}
+
+// Inserts new policy into db.
+func (a *App) createPolicy(w http.ResponseWriter, r *http.Request) {
+	var dt model.LendingPolicy
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreatePolicy(d.Database); err != nil {
+		respondWithPolicyError(w, err)
+		return
+	}
+	// Respond with newly created policy.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Updates policy in db using id from URL.
+func (a *App) updatePolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	var dt model.LendingPolicy
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+	dt.ID = id
+
+	if err := dt.UpdatePolicy(d.Database); err != nil {
+		respondWithPolicyError(w, err)
+		return
+	}
+	// Respond with updated policy.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Deletes policy in db using id from URL.
+func (a *App) deletePolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	dt := model.LendingPolicy{ID: id}
+	if err := dt.DeletePolicy(d.Database); err != nil {
+		respondWithPolicyError(w, err)
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Responds with the status matching a policy error.
+func respondWithPolicyError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		// Respond with 404 if policy not found in db.
+		app.RespondWithError(w, http.StatusNotFound, "policy not found")
+	case model.ErrPolicyExists:
+		app.RespondWithError(w, http.StatusConflict, err.Error())
+	default:
+		// Respond if internal server error.
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
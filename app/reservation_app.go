@@ -0,0 +1,125 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Initialize DB and routes.
+
+func (a *App) ReservationInitialize() {
+	a.initializeReservationRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeReservationRoutes() {
+	a.Router.HandleFunc("/reservation", a.createReservation).Methods("POST")
+	a.Router.HandleFunc("/reservation/{id}", a.getReservation).Methods("GET")
+	a.Router.HandleFunc("/reservation/{id}", a.cancelReservation).Methods("DELETE")
+	a.Router.HandleFunc("/book/{id}/reservations", a.getBookReservations).Methods("GET")
+}
+
+// Route handlers
+
+// Retrieves reservation with its queue position from db using id from URL.
+func (a *App) getReservation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid reservation ID")
+		return
+	}
+
+	dt := model.Reservation{ID: id}
+	if err := dt.GetReservation(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if reservation not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "reservation not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// If data found respond with reservation object.
+	app.RespondWithJSON(w, http.StatusOK, dt)
+}
+
+// Gets hold queue of a book using id from URL.
+func (a *App) getBookReservations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	reservations, err := model.GetBookReservations(d.Database, id)
+	if err != nil {
+		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, reservations)
+}
+
+// Puts reader into the hold queue of a book.
+func (a *App) createReservation(w http.ResponseWriter, r *http.Request) {
+	var dt model.Reservation
+	// Gets JSON object from request body.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dt); err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := dt.CreateReservation(d.Database); err != nil {
+		switch err {
+		case model.ErrUserNotFound, model.ErrBookNotFound:
+			app.RespondWithError(w, http.StatusNotFound, err.Error())
+		case model.ErrAlreadyReserved:
+			app.RespondWithError(w, http.StatusConflict, err.Error())
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with newly created reservation.
+	app.RespondWithJSON(w, http.StatusCreated, dt)
+}
+
+// Cancels reservation using id from URL.
+func (a *App) cancelReservation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid reservation ID")
+		return
+	}
+
+	dt := model.Reservation{ID: id}
+	if err := dt.CancelReservation(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if there is no active reservation with the id.
+			app.RespondWithError(w, http.StatusNotFound, "Active reservation not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
@@ -32,6 +32,9 @@ func (a *App) initializeUserRoutes() {
 	a.Router.HandleFunc("/user/{id}", a.getUser).Methods("GET")
 	a.Router.HandleFunc("/user/{id}", a.updateUser).Methods("PUT")
 	a.Router.HandleFunc("/user/{id}", a.deleteUser).Methods("DELETE")
+	a.Router.HandleFunc("/user/{id}/restore", a.restoreUser).Methods("POST")
+	a.Router.HandleFunc("/user/{id}/loans", a.getUserLoans).Methods("GET")
+	a.Router.HandleFunc("/user/{id}/history", a.getUserHistory).Methods("GET")
 }
 
 // Route handlers
@@ -133,7 +136,14 @@ func (a *App) updateUser(w http.ResponseWriter, r *http.Request) {
 	dt.ID = id
 
 	if err := dt.UpdateUser(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if user not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with updated user.
@@ -151,9 +161,96 @@ func (a *App) deleteUser(w http.ResponseWriter, r *http.Request) {
 
 	dt := model.User{ID: id}
 	if err := dt.DeleteUser(d.Database); err != nil {
-		app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if user not found in db.
+			app.RespondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	// Respond with success message if operation is completed.
+	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// Restores soft deleted user in db using id from URL.
+func (a *App) restoreUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// Convert id string variable to int.
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	dt := model.User{ID: id}
+	if err := dt.RestoreUser(d.Database); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// Respond with 404 if there is no deleted user with the id.
+			app.RespondWithError(w, http.StatusNotFound, "Deleted user not found")
+		default:
+			// Respond if internal server error.
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 	// Respond with success message if operation is completed.
 	app.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
-}
\ No newline at end of file
+}
+
+// Gets current loans of user from URL. Query takes limit and page, and
+// from and to dates the loans were issued in.
+func (a *App) getUserLoans(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	limit, page := pageParams(r)
+	loans, err := model.GetUserLoans(d.Database, id, r.URL.Query().Get("from"), r.URL.Query().Get("to"), limit, page)
+	if err != nil {
+		respondWithLedgerError(w, err)
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, loans)
+}
+
+// Gets borrowing history of user from URL. Query takes limit and page, and
+// from and to dates the loans were issued in.
+func (a *App) getUserHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		app.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	limit, page := pageParams(r)
+	history, err := model.GetUserHistory(d.Database, id, r.URL.Query().Get("from"), r.URL.Query().Get("to"), limit, page)
+	if err != nil {
+		respondWithLedgerError(w, err)
+		return
+	}
+
+	app.RespondWithJSON(w, http.StatusOK, history)
+}
+
+// Reads limit and page from URL query, 20 and 1 by default.
+func pageParams(r *http.Request) (int, int) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if limit < 1 {
+		limit = 20
+	}
+	// Min start is 0;
+	if page < 1 {
+		page = 1
+	}
+	return limit, page
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	app "github.com/library/app/utils"
+	"github.com/library/model"
+)
+
+// Route prefixes of versioned entities.
+var versionRoutes = map[string]string{
+	model.VersionBook:   "/book/{id}",
+	model.VersionAuthor: "/author/{id}",
+	model.VersionBooks:  "/book/number/{id}",
+}
+
+// Initialize DB and routes.
+func (a *App) VersionInitialize() {
+	a.initializeVersionRoutes()
+}
+
+// Defines routes.
+func (a *App) initializeVersionRoutes() {
+	for entity, prefix := range versionRoutes {
+		a.Router.HandleFunc(prefix+"/versions", a.getVersions(entity)).Methods("GET")
+		a.Router.HandleFunc(prefix+"/versions/diff", a.diffVersions(entity)).Methods("GET")
+		a.Router.HandleFunc(prefix+"/versions/{version}/revert", a.revertVersion(entity)).Methods("POST")
+	}
+}
+
+// Route handlers
+
+// Gets change history of a record using id from URL.
+func (a *App) getVersions(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := uuid.Parse(vars["id"])
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid ID")
+			return
+		}
+
+		versions, err := model.GetVersions(d.Database, entity, id)
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		app.RespondWithJSON(w, http.StatusOK, versions)
+	}
+}
+
+// Compares two versions of a record. Versions come from "from" and "to" URL parameters.
+func (a *App) diffVersions(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := uuid.Parse(vars["id"])
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid ID")
+			return
+		}
+		from, err := strconv.Atoi(r.URL.Query().Get("from"))
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid 'from' version")
+			return
+		}
+		to, err := strconv.Atoi(r.URL.Query().Get("to"))
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid 'to' version")
+			return
+		}
+
+		versions := []model.Version{
+			{Entity: entity, RecordID: id, Version: from},
+			{Entity: entity, RecordID: id, Version: to},
+		}
+		for i := range versions {
+			if err := versions[i].GetVersion(d.Database); err != nil {
+				switch err {
+				case sql.ErrNoRows:
+					// Respond with 404 if version not found in db.
+					app.RespondWithError(w, http.StatusNotFound, "Version not found")
+				default:
+					// Respond if internal server error.
+					app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+				}
+				return
+			}
+		}
+
+		changes, err := model.DiffVersions(versions[0], versions[1])
+		if err != nil {
+			app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		app.RespondWithJSON(w, http.StatusOK, changes)
+	}
+}
+
+// Reverts a record to a previous version using id and version from URL.
+func (a *App) revertVersion(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := uuid.Parse(vars["id"])
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid ID")
+			return
+		}
+		version, err := strconv.Atoi(vars["version"])
+		if err != nil {
+			app.RespondWithError(w, http.StatusBadRequest, "Invalid version")
+			return
+		}
+
+		admin, ok := requestAdmin(r)
+		if !ok {
+			app.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		var reverted interface{}
+		switch entity {
+		case model.VersionBook:
+			dt := model.Book{ID: id}
+			err = dt.RevertBook(d.Database, version, admin.Email)
+			reverted = dt
+		case model.VersionAuthor:
+			dt := model.Author{ID: id}
+			err = dt.RevertAuthor(d.Database, version, admin.Email)
+			reverted = dt
+		case model.VersionBooks:
+			dt := model.Books{ID: id}
+			err = dt.RevertNumberBook(d.Database, version, admin.Email)
+			reverted = dt
+		}
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				// Respond with 404 if record or version not found in db.
+				app.RespondWithError(w, http.StatusNotFound, "Version not found")
+			default:
+				// Respond if internal server error.
+				app.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		// Respond with reverted record.
+		app.RespondWithJSON(w, http.StatusOK, reverted)
+	}
+}
@@ -17,23 +17,22 @@ func Email(to []string) {
 func CheckReturnDate(r db.DB) ([]string, error) {
 	transaction, err := r.Database.Begin()
 	if err != nil {
-		log.Fatalf("Can not begin transaction:%s", err)
 		return nil, err
 	}
+	defer transaction.Rollback()
 	var listEmail []string
-	query := "SELECT users.email FROM users JOIN issue ON issue.return_date < $1 AND users.id = issue.user_id"
-	rows, err := transaction.Query(query, time.Now().Add(time.Hour))
+	query := "SELECT users.email FROM users JOIN issue ON issue.return_date < $1::date AND users.id = issue.user_id WHERE issue.closed_at IS NULL AND issue.deleted_at IS NULL AND users.deleted_at IS NULL"
+	rows, err := transaction.Query(query, time.Now().Format("2006-01-02"))
 	if err != nil {
-		log.Fatalf("Can not executes a query:%s", err)
 		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var email string
-		if err2 := rows.Scan(&email); err2 != nil {
-			log.Fatalf("Scan error:%s", err2)
-			return nil, err2
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
 		}
 		listEmail = append(listEmail, email)
 	}
-	return listEmail, nil
+	return listEmail, rows.Err()
 }
\ No newline at end of file
@@ -0,0 +1,211 @@
+// Command images maintains uploaded image files.
+//
+// Usage:
+//
+//	images migrate -from local -to s3 [-delete]
+//	images verify
+//
+// migrate copies every stored file from one storage backend to another.
+// verify checks that every recorded image has its files and that stored
+// content still matches its SHA-256. It exits with status 1 on problems.
+// Backends and the database are configured in config.yaml.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+
+	_ "github.com/lib/pq"
+	"github.com/library/db"
+	"github.com/library/images"
+	"github.com/library/model"
+	"github.com/library/storage"
+	"github.com/spf13/viper"
+)
+
+func main() {
+	viper.SetConfigName("config")
+	viper.AddConfigPath(".")
+	// Find and read the config file
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("Error while reading config file %s", err)
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "migrate":
+		migrate(os.Args[2:])
+	case "verify":
+		verify(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: images migrate -from <backend> -to <backend> [-delete]")
+	fmt.Fprintln(os.Stderr, "       images verify")
+	os.Exit(2)
+}
+
+// Copies files between storage backends. Files already present in the
+// destination are skipped, so an interrupted migration can be rerun.
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := flags.String("from", "local", "source backend: local or s3")
+	to := flags.String("to", "s3", "destination backend: local or s3")
+	remove := flags.Bool("delete", false, "delete files from the source after copying")
+	flags.Parse(args)
+
+	if *from == *to {
+		log.Fatalf("Source and destination are the same backend: %s", *from)
+	}
+	src, err := storage.New(*from)
+	if err != nil {
+		log.Fatalf("Can not open source storage: %s", err)
+	}
+	dst, err := storage.New(*to)
+	if err != nil {
+		log.Fatalf("Can not open destination storage: %s", err)
+	}
+
+	names, err := src.List()
+	if err != nil {
+		log.Fatalf("Can not list source storage: %s", err)
+	}
+	copied, skipped := 0, 0
+	for _, name := range names {
+		if existing, err := dst.Get(name); err == nil {
+			existing.Close()
+			skipped++
+		} else if err != storage.ErrNotExist {
+			log.Fatalf("Can not check %s in destination: %s", name, err)
+		} else {
+			if err := copyObject(src, dst, name); err != nil {
+				log.Fatalf("Can not copy %s: %s", name, err)
+			}
+			copied++
+		}
+		if *remove {
+			if err := src.Delete(name); err != nil {
+				log.Fatalf("Can not delete %s from source: %s", name, err)
+			}
+		}
+	}
+	log.Printf("Migrated images from %s to %s: %d copied, %d already present", *from, *to, copied, skipped)
+}
+
+func copyObject(src, dst storage.Storage, name string) error {
+	obj, err := src.Get(name)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	return dst.Put(name, obj, obj.Size, mime.TypeByExtension(filepath.Ext(name)))
+}
+
+// Checks stored files against the image records in the database.
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.Parse(args)
+
+	var d db.DB
+	d.Initialize(dbCredentials())
+	s, err := storage.New(viper.GetString("IMAGE_STORAGE"))
+	if err != nil {
+		log.Fatalf("Can not open image storage: %s", err)
+	}
+
+	blobs, err := model.GetImageBlobs(d.Database)
+	if err != nil {
+		log.Fatalf("Can not read image records: %s", err)
+	}
+	problems := 0
+	for _, blob := range blobs {
+		hash, err := hashObject(s, blob.Filename)
+		switch {
+		case err == storage.ErrNotExist:
+			fmt.Printf("MISSING   %s\n", blob.Filename)
+			problems++
+		case err != nil:
+			log.Fatalf("Can not read %s: %s", blob.Filename, err)
+		case hash != blob.SHA256:
+			fmt.Printf("CORRUPTED %s: expected sha256 %s, got %s\n", blob.Filename, blob.SHA256, hash)
+			problems++
+		}
+		problems += checkRenditions(s, blob.Filename)
+	}
+
+	// Images uploaded before hashing can only be checked for presence.
+	unhashed, err := model.GetUnhashedImages(d.Database)
+	if err != nil {
+		log.Fatalf("Can not read image records: %s", err)
+	}
+	for _, dt := range unhashed {
+		if obj, err := s.Get(dt.Filename); err == storage.ErrNotExist {
+			fmt.Printf("MISSING   %s\n", dt.Filename)
+			problems++
+		} else if err != nil {
+			log.Fatalf("Can not read %s: %s", dt.Filename, err)
+		} else {
+			obj.Close()
+		}
+	}
+
+	log.Printf("Verified %d stored images and %d unhashed images: %d problems", len(blobs), len(unhashed), problems)
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
+
+// Reports renditions missing for an original file.
+func checkRenditions(s storage.Storage, filename string) int {
+	missing := 0
+	for _, rendition := range images.Renditions {
+		name, _ := images.RenditionName(filename, rendition.Name)
+		obj, err := s.Get(name)
+		if err == storage.ErrNotExist {
+			fmt.Printf("MISSING   %s\n", name)
+			missing++
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Can not read %s: %s", name, err)
+		}
+		obj.Close()
+	}
+	return missing
+}
+
+// Returns hex SHA-256 of a stored object.
+func hashObject(s storage.Storage, name string) (string, error) {
+	obj, err := s.Get(name)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, obj); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns database credentials the same way the application does.
+func dbCredentials() (user, password, host, name string) {
+	if os.Getenv("ENV") == "prod" {
+		return os.Getenv("PROD_DB_USERNAME"), os.Getenv("PROD_DB_PASSWORD"), os.Getenv("PROD_DB_HOST"), os.Getenv("PROD_DB_NAME")
+	}
+	return viper.GetString("APP_DB_USERNAME"), viper.GetString("APP_DB_PASSWORD"), viper.GetString("APP_DB_HOST"), viper.GetString("APP_DB_NAME")
+}
@@ -37,7 +37,6 @@ const USER_SCHEMA = `
 	    date_of_birth varchar(225) NOT NULL,
 		email varchar(225) NOT NULL,
 	    address varchar(225) NOT NULL,
-	    indebtedness varchar(225) NOT NULL,
 		created_at timestamp NOT NULL,
 		updated_at timestamp NOT NULL,
 		primary key (id)
@@ -117,7 +116,7 @@ const ISSUE_SCHEMA = `
 		id uuid DEFAULT uuid_generate_v4 () unique,
 	    user_id uuid,
 	    book_id uuid,
-	    return_date varchar(225) NOT NULL,
+	    return_date date NOT NULL,
 	    preliminary_cost float NOT NULL,
 		created_at timestamp NOT NULL,
 	    updated_at timestamp NOT NULL,
@@ -163,6 +162,446 @@ ALTER TABLE acceptance
             REFERENCES book(id)
             ON DELETE CASCADE;
 `
+// Soft delete columns. Lending history keeps its rows when a reader or
+// book is removed, so history foreign keys no longer cascade.
+const SOFT_DELETE_SCHEMA = `
+	ALTER TABLE book ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+	ALTER TABLE authors ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+ALTER TABLE issue
+    DROP CONSTRAINT IF EXISTS fk_users_issue,
+    ADD CONSTRAINT fk_users_issue
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE RESTRICT;
+
+ALTER TABLE issue
+    DROP CONSTRAINT IF EXISTS fk_book_issue,
+    ADD CONSTRAINT fk_book_issue
+        FOREIGN KEY (book_id)
+            REFERENCES book(id)
+            ON DELETE RESTRICT;
+
+ALTER TABLE acceptance
+    DROP CONSTRAINT IF EXISTS fk_users_acceptance,
+    ADD CONSTRAINT fk_users_acceptance
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE RESTRICT;
+
+ALTER TABLE acceptance
+    DROP CONSTRAINT IF EXISTS fk_book_acceptance,
+    ADD CONSTRAINT fk_book_acceptance
+        FOREIGN KEY (book_id)
+            REFERENCES book(id)
+            ON DELETE RESTRICT;
+`
+// Schema for change history of catalog records.
+const VERSION_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS record_versions (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    entity varchar(225) NOT NULL,
+	    record_id uuid NOT NULL,
+	    version int NOT NULL,
+	    data jsonb NOT NULL,
+	    changed_by varchar(225) NOT NULL,
+		created_at timestamp NOT NULL,
+		primary key (id),
+		unique (entity, record_id, version)
+	);
+`
+// Schema for uploaded images. Books, authors and acceptances refer to them by id.
+const IMAGE_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS images (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    filename varchar(225) NOT NULL,
+	    original_name varchar(225) NOT NULL,
+	    content_type varchar(225) NOT NULL,
+	    size bigint NOT NULL,
+	    width int NOT NULL,
+	    height int NOT NULL,
+		created_at timestamp NOT NULL,
+		primary key (id)
+	);
+`
+// Schema for deduplicated image content. Uploads with the same SHA-256
+// share one stored file, which is removed when ref_count drops to zero.
+const IMAGE_BLOB_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS image_blobs (
+		sha256 varchar(64) NOT NULL,
+	    filename varchar(225) NOT NULL,
+	    ref_count int NOT NULL,
+		created_at timestamp NOT NULL,
+		primary key (sha256)
+	);
+
+	ALTER TABLE images ADD COLUMN IF NOT EXISTS sha256 varchar(64);
+	CREATE INDEX IF NOT EXISTS images_sha256_idx ON images (sha256);
+`
+// Schema for loan state. Open loans have no closed_at; stock never goes negative.
+const LOAN_SCHEMA = `
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS closed_at timestamp;
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS renewals int NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS issue_open_idx ON issue (book_id) WHERE closed_at IS NULL AND deleted_at IS NULL;
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'books_number_of_book_check') THEN
+			ALTER TABLE books
+			    ADD CONSTRAINT books_number_of_book_check
+			        CHECK (number_of_book >= 0);
+		END IF;
+	END $$;
+`
+// Schema for reservations. Readers wait in a queue per book ordered by
+// created_at; a ready reservation holds a copy until pickup_deadline.
+const RESERVATION_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS reservations (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    user_id uuid NOT NULL,
+	    book_id uuid NOT NULL,
+	    status varchar(225) NOT NULL,
+	    pickup_deadline timestamp,
+		created_at timestamp NOT NULL,
+	    updated_at timestamp NOT NULL,
+		primary key (id)
+	);
+	CREATE INDEX IF NOT EXISTS reservations_queue_idx ON reservations (book_id, created_at) WHERE status IN ('waiting', 'ready');
+
+ALTER TABLE reservations
+    DROP CONSTRAINT IF EXISTS fk_users_reservations,
+    ADD CONSTRAINT fk_users_reservations
+        FOREIGN KEY (user_id)
+            REFERENCES users(id)
+            ON DELETE CASCADE;
+
+ALTER TABLE reservations
+    DROP CONSTRAINT IF EXISTS fk_book_reservations,
+    ADD CONSTRAINT fk_book_reservations
+        FOREIGN KEY (book_id)
+            REFERENCES book(id)
+            ON DELETE CASCADE;
+`
+// Schema for lending policies. A NULL category_id or empty reader_type
+// matches every category or reader type.
+const POLICY_SCHEMA = `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS reader_type varchar(225) NOT NULL DEFAULT 'regular';
+
+	CREATE TABLE IF NOT EXISTS lending_policies (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    category_id uuid REFERENCES categories(id) ON DELETE CASCADE,
+	    reader_type varchar(225) NOT NULL DEFAULT '',
+	    loan_period int NOT NULL,
+	    max_loans int NOT NULL,
+	    max_renewals int NOT NULL,
+	    price_multiplier float NOT NULL,
+	    grace_days int NOT NULL,
+		created_at timestamp NOT NULL,
+	    updated_at timestamp NOT NULL,
+		primary key (id)
+	);
+`
+// Schema for server side loan costs. Issues keep the daily price and the
+// reader discount they were calculated with.
+const LOAN_COST_SCHEMA = `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS discount float NOT NULL DEFAULT 0;
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS discount float NOT NULL DEFAULT 0;
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS price_per_day float;
+
+	UPDATE issue SET price_per_day = book.price_per_day FROM book WHERE book.id = issue.book_id AND issue.price_per_day IS NULL;
+	UPDATE issue SET price_per_day = 0 WHERE price_per_day IS NULL;
+	ALTER TABLE issue ALTER COLUMN price_per_day SET NOT NULL;
+`
+// Schema for returns. An acceptance refers to the issue it closes and
+// keeps the parts its final cost was calculated from.
+const RETURN_SCHEMA = `
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS issue_id uuid REFERENCES issue(id) ON DELETE RESTRICT;
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS days_kept int NOT NULL DEFAULT 0;
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS late_days int NOT NULL DEFAULT 0;
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS late_fee float NOT NULL DEFAULT 0;
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS surcharge float NOT NULL DEFAULT 0;
+	CREATE UNIQUE INDEX IF NOT EXISTS acceptance_issue_idx ON acceptance (issue_id) WHERE deleted_at IS NULL;
+`
+// Schema for discount rules and the discounts applied on each acceptance.
+const DISCOUNT_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS discount_rules (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    name varchar(225) NOT NULL,
+	    kind varchar(225) NOT NULL,
+	    percent float NOT NULL,
+	    min_books int NOT NULL DEFAULT 0,
+	    min_loans int NOT NULL DEFAULT 0,
+	    category_id uuid REFERENCES categories(id) ON DELETE CASCADE,
+	    starts_on date,
+	    ends_on date,
+	    stackable boolean NOT NULL DEFAULT false,
+	    active boolean NOT NULL DEFAULT true,
+		created_at timestamp NOT NULL,
+	    updated_at timestamp NOT NULL,
+		primary key (id)
+	);
+
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS discount_explanation jsonb NOT NULL DEFAULT '[]';
+`
+
+// Schema for the reader debt ledger. Numeric values of the former
+// indebtedness column are carried over as migrated entries, other text
+// is kept in the entry description, then the column is dropped. Entries
+// keep their reader from being deleted.
+const LEDGER_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS ledger_entries (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    user_id uuid NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+	    kind varchar(225) NOT NULL,
+	    amount float NOT NULL,
+	    description text NOT NULL DEFAULT '',
+	    issue_id uuid REFERENCES issue(id) ON DELETE SET NULL,
+	    acceptance_id uuid REFERENCES acceptance(id) ON DELETE SET NULL,
+		created_at timestamp NOT NULL,
+		primary key (id)
+	);
+	CREATE INDEX IF NOT EXISTS ledger_entries_user_idx ON ledger_entries (user_id, created_at);
+
+	ALTER TABLE ledger_entries
+	    DROP CONSTRAINT IF EXISTS ledger_entries_user_id_fkey,
+	    ADD CONSTRAINT ledger_entries_user_id_fkey
+	        FOREIGN KEY (user_id)
+	            REFERENCES users(id)
+	            ON DELETE RESTRICT;
+
+	DO $$
+	BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='users' AND column_name='indebtedness') THEN
+			INSERT INTO ledger_entries (user_id, kind, amount, description, created_at)
+			SELECT id, 'migrated',
+				CASE WHEN trim(indebtedness) ~ '^-?[0-9]+(\.[0-9]+)?$' THEN trim(indebtedness)::float ELSE 0 END,
+				'indebtedness before ledger: ' || indebtedness, now()
+			FROM users WHERE trim(indebtedness) NOT IN ('', '0');
+			ALTER TABLE users DROP COLUMN indebtedness;
+		END IF;
+	END $$;
+`
+
+// Schema for payments and refunds. Receipts are numbered from a single
+// counter row so numbers have no gaps.
+const PAYMENT_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS payments (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    receipt_number bigint UNIQUE,
+	    user_id uuid NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+	    acceptance_id uuid REFERENCES acceptance(id) ON DELETE RESTRICT,
+	    refund_of uuid REFERENCES payments(id) ON DELETE RESTRICT,
+	    kind varchar(225) NOT NULL,
+	    method varchar(225) NOT NULL,
+	    amount float NOT NULL CHECK (amount > 0),
+	    status varchar(225) NOT NULL,
+	    settled_at timestamp,
+		created_at timestamp NOT NULL,
+	    updated_at timestamp NOT NULL,
+		primary key (id)
+	);
+	CREATE INDEX IF NOT EXISTS payments_user_idx ON payments (user_id, created_at);
+	CREATE INDEX IF NOT EXISTS payments_acceptance_idx ON payments (acceptance_id);
+
+	CREATE TABLE IF NOT EXISTS receipt_counter (
+		id boolean PRIMARY KEY DEFAULT true CHECK (id),
+	    last_number bigint NOT NULL
+	);
+	INSERT INTO receipt_counter (id, last_number) VALUES (true, 0) ON CONFLICT DO NOTHING;
+
+	ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS payment_id uuid REFERENCES payments(id) ON DELETE SET NULL;
+`
+
+// Schema for lending blocks. Privileged admins may override a block;
+// each override is recorded together with the block it lifted and keeps
+// its loan and reader from being deleted.
+const LENDING_BLOCK_SCHEMA = `
+	ALTER TABLE admins ADD COLUMN IF NOT EXISTS privileged boolean NOT NULL DEFAULT false;
+
+	CREATE TABLE IF NOT EXISTS lending_overrides (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    issue_id uuid NOT NULL REFERENCES issue(id) ON DELETE RESTRICT,
+	    user_id uuid NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+	    admin_id uuid NOT NULL REFERENCES admins(id) ON DELETE RESTRICT,
+	    reason text NOT NULL,
+	    block jsonb NOT NULL,
+		created_at timestamp NOT NULL,
+		primary key (id)
+	);
+	CREATE INDEX IF NOT EXISTS lending_overrides_user_idx ON lending_overrides (user_id, created_at);
+
+	ALTER TABLE lending_overrides
+	    DROP CONSTRAINT IF EXISTS lending_overrides_issue_id_fkey,
+	    ADD CONSTRAINT lending_overrides_issue_id_fkey
+	        FOREIGN KEY (issue_id)
+	            REFERENCES issue(id)
+	            ON DELETE RESTRICT;
+
+	ALTER TABLE lending_overrides
+	    DROP CONSTRAINT IF EXISTS lending_overrides_user_id_fkey,
+	    ADD CONSTRAINT lending_overrides_user_id_fkey
+	        FOREIGN KEY (user_id)
+	            REFERENCES users(id)
+	            ON DELETE RESTRICT;
+`
+
+// Schema for lost books. A lost loan is closed with lost_at set; found_at
+// is set when the declaration is reversed.
+const LOST_SCHEMA = `
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS lost_at timestamp;
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS found_at timestamp;
+`
+
+// Schema for damages and photos recorded when a book is returned.
+const DAMAGE_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS acceptance_damages (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+		acceptance_id uuid NOT NULL REFERENCES acceptance(id) ON DELETE CASCADE,
+		book_id uuid NOT NULL,
+		damage_type varchar(50) NOT NULL,
+		note text NOT NULL DEFAULT '',
+		surcharge float NOT NULL DEFAULT 0,
+		created_at timestamp NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS acceptance_damages_book_idx ON acceptance_damages (book_id, created_at);
+	CREATE TABLE IF NOT EXISTS acceptance_photos (
+		acceptance_id uuid NOT NULL REFERENCES acceptance(id) ON DELETE CASCADE,
+		position int NOT NULL,
+		photo varchar(255) NOT NULL,
+		PRIMARY KEY (acceptance_id, position)
+	);
+	INSERT INTO acceptance_photos (acceptance_id, position, photo)
+		SELECT id, 0, photo FROM acceptance WHERE photo <> ''
+		ON CONFLICT DO NOTHING;
+`
+
+// Schema for branches. Stock, loans, returns and held copies are kept per
+// branch; a Main branch is created when there is none and stock without a
+// branch is moved to the oldest branch. Transfers move
+// copies between branches and are in transit once shipped.
+const BRANCH_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS branches (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    name varchar(225) NOT NULL UNIQUE,
+	    address varchar(225) NOT NULL DEFAULT '',
+		created_at timestamp NOT NULL,
+		updated_at timestamp NOT NULL,
+		deleted_at timestamp,
+		primary key (id)
+	);
+	ALTER TABLE books ADD COLUMN IF NOT EXISTS branch_id uuid REFERENCES branches(id) ON DELETE RESTRICT;
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM branches WHERE deleted_at IS NULL) THEN
+			INSERT INTO branches (name, created_at, updated_at) VALUES ('Main', now(), now())
+				ON CONFLICT (name) DO UPDATE SET deleted_at = NULL, updated_at = now();
+		END IF;
+		UPDATE books SET branch_id = (SELECT id FROM branches WHERE deleted_at IS NULL ORDER BY created_at LIMIT 1)
+			WHERE branch_id IS NULL;
+	END $$;
+
+	ALTER TABLE books ALTER COLUMN branch_id SET NOT NULL;
+	CREATE INDEX IF NOT EXISTS books_branch_idx ON books (book_id, branch_id);
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS branch_id uuid REFERENCES branches(id) ON DELETE RESTRICT;
+	ALTER TABLE acceptance ADD COLUMN IF NOT EXISTS branch_id uuid REFERENCES branches(id) ON DELETE RESTRICT;
+	ALTER TABLE reservations ADD COLUMN IF NOT EXISTS branch_id uuid REFERENCES branches(id) ON DELETE RESTRICT;
+
+	CREATE TABLE IF NOT EXISTS transfers (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    book_id uuid NOT NULL REFERENCES book(id) ON DELETE RESTRICT,
+	    from_branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+	    to_branch_id uuid NOT NULL REFERENCES branches(id) ON DELETE RESTRICT,
+	    quantity int NOT NULL CHECK (quantity > 0),
+	    status varchar(50) NOT NULL,
+		shipped_at timestamp,
+		received_at timestamp,
+		created_at timestamp NOT NULL,
+		updated_at timestamp NOT NULL,
+		primary key (id)
+	);
+	CREATE INDEX IF NOT EXISTS transfers_status_idx ON transfers (status, created_at);
+`
+
+// Schema for reading-room lending. Books for the reading room only are lent
+// as reading-room loans, which are flagged when still open at closing time.
+const READING_ROOM_SCHEMA = `
+	ALTER TABLE book ADD COLUMN IF NOT EXISTS reading_room_only boolean NOT NULL DEFAULT false;
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS loan_type varchar(50) NOT NULL DEFAULT 'home';
+	ALTER TABLE issue ADD COLUMN IF NOT EXISTS flagged_at timestamp;
+	CREATE INDEX IF NOT EXISTS issue_loan_type_idx ON issue (loan_type, created_at);
+`
+
+// Schema for the calendar. Opening hours and holidays without a branch are
+// those of the whole library; a branch without opening hours of its own
+// keeps the library's.
+const CALENDAR_SCHEMA = `
+	CREATE TABLE IF NOT EXISTS opening_hours (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    branch_id uuid REFERENCES branches(id) ON DELETE CASCADE,
+	    weekday int NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+	    opens varchar(5) NOT NULL,
+	    closes varchar(5) NOT NULL,
+		primary key (id)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS opening_hours_day_idx
+		ON opening_hours (COALESCE(branch_id, '00000000-0000-0000-0000-000000000000'), weekday);
+
+	CREATE TABLE IF NOT EXISTS holidays (
+		id uuid DEFAULT uuid_generate_v4 () unique,
+	    branch_id uuid REFERENCES branches(id) ON DELETE CASCADE,
+	    day date NOT NULL,
+	    name varchar(225) NOT NULL,
+		created_at timestamp NOT NULL,
+		primary key (id)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS holidays_day_idx
+		ON holidays (COALESCE(branch_id, '00000000-0000-0000-0000-000000000000'), day);
+`
+
+// Schema for return dates as dates. They used to be stored as text; a value
+// that isn't a valid date is repaired to the day the loan was issued before
+// the column is converted.
+const RETURN_DATE_SCHEMA = `
+	DO $$
+	DECLARE
+		r record;
+	BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'issue' AND column_name = 'return_date' AND data_type <> 'date') THEN
+			FOR r IN SELECT id, return_date FROM issue LOOP
+				BEGIN
+					PERFORM r.return_date::date;
+				EXCEPTION WHEN others THEN
+					RAISE WARNING 'issue % has invalid return date %, set to its issue date', r.id, r.return_date;
+					UPDATE issue SET return_date = created_at::date::text WHERE id = r.id;
+				END;
+			END LOOP;
+			ALTER TABLE issue ALTER COLUMN return_date TYPE date USING return_date::date;
+		END IF;
+	END $$;
+`
+
+// Schema for photo references in canonical form. Photos stored as an image
+// ID in another accepted spelling, upper case, braced, as a URN or without
+// hyphens, are rewritten so cleanup finds them referenced. File names
+// stored before uploads had IDs are left as they are.
+const PHOTO_SCHEMA = `
+	DO $$
+	DECLARE
+		t text;
+		id text := '[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}';
+	BEGIN
+		FOREACH t IN ARRAY ARRAY['book', 'authors', 'acceptance', 'acceptance_photos'] LOOP
+			EXECUTE format('UPDATE %I SET photo = regexp_replace(photo, %L, %L, %L)::uuid::text WHERE photo ~* %L', t,
+				'^urn:uuid:', '', 'i', '^(urn:uuid:' || id || '|\{' || id || '\}|' || id || ')$');
+		END LOOP;
+	END $$;
+`
+
 // Receives database credentials and connects to database.
 func (db *DB) Initialize(user, password, dbhost, dbname string) {
 	connectionString := fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", user, password, dbhost, dbname)
@@ -182,4 +621,24 @@ func (db *DB) Initialize(user, password, dbhost, dbname string) {
 	db.Database.Exec(BOOKS_SCHEMA)
 	db.Database.Exec(ISSUE_SCHEMA)
 	db.Database.Exec(ACCEPTANCE_SCHEMA)
+	db.Database.Exec(SOFT_DELETE_SCHEMA)
+	db.Database.Exec(VERSION_SCHEMA)
+	db.Database.Exec(IMAGE_SCHEMA)
+	db.Database.Exec(IMAGE_BLOB_SCHEMA)
+	db.Database.Exec(LOAN_SCHEMA)
+	db.Database.Exec(RESERVATION_SCHEMA)
+	db.Database.Exec(POLICY_SCHEMA)
+	db.Database.Exec(LOAN_COST_SCHEMA)
+	db.Database.Exec(RETURN_SCHEMA)
+	db.Database.Exec(DISCOUNT_SCHEMA)
+	db.Database.Exec(LEDGER_SCHEMA)
+	db.Database.Exec(PAYMENT_SCHEMA)
+	db.Database.Exec(LENDING_BLOCK_SCHEMA)
+	db.Database.Exec(LOST_SCHEMA)
+	db.Database.Exec(DAMAGE_SCHEMA)
+	db.Database.Exec(BRANCH_SCHEMA)
+	db.Database.Exec(READING_ROOM_SCHEMA)
+	db.Database.Exec(CALENDAR_SCHEMA)
+	db.Database.Exec(RETURN_DATE_SCHEMA)
+	db.Database.Exec(PHOTO_SCHEMA)
 }
@@ -0,0 +1,113 @@
+package images
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Size of the stored upload.
+const Original = "original"
+
+// Defines a resized copy of an uploaded image. MaxSize limits the longest side in pixels.
+type Rendition struct {
+	Name    string
+	MaxSize int
+}
+
+// Renditions generated for every upload in addition to the original.
+var Renditions = []Rendition{
+	{Name: "thumbnail", MaxSize: 150},
+	{Name: "medium", MaxSize: 600},
+}
+
+var ErrUnknownSize = errors.New("unknown image size")
+
+// Largest number of pixels an upload may have. Decoding allocates memory
+// for every pixel, so a small file may still be too large to decode.
+var MaxPixels = 25000000
+
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Returns ErrTooManyPixels if an image of the given size is over MaxPixels.
+func CheckPixels(width, height int) error {
+	if int64(width)*int64(height) > int64(MaxPixels) {
+		return ErrTooManyPixels
+	}
+	return nil
+}
+
+// Returns file name of a rendition of the original file.
+// Empty size or Original returns the original file name.
+func RenditionName(filename, size string) (string, error) {
+	if size == "" || size == Original {
+		return filename, nil
+	}
+	for _, r := range Renditions {
+		if r.Name == size {
+			ext := filepath.Ext(filename)
+			return strings.TrimSuffix(filename, ext) + "_" + size + ext, nil
+		}
+	}
+	return "", ErrUnknownSize
+}
+
+// Scales image down so that its longest side is at most max pixels.
+// Images that already fit are returned unchanged.
+func Resize(src image.Image, max int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw <= max && sh <= max {
+		return src
+	}
+	dw, dh := max, sh*max/sw
+	if sh > sw {
+		dw, dh = sw*max/sh, max
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	// Box filter: every destination pixel averages the source pixels it covers.
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// Encodes image in the format of the given content type.
+func Encode(w io.Writer, img image.Image, contentType string) error {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		return png.Encode(w, img)
+	}
+	return errors.New("unsupported image format: " + contentType)
+}
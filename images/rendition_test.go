@@ -0,0 +1,84 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// Test file names of renditions.
+// Tests if sizes get a suffix before the extension and unknown sizes fail.
+func TestRenditionName(t *testing.T) {
+	for _, c := range []struct {
+		size     string
+		expected string
+	}{
+		{"", "cover.png"},
+		{Original, "cover.png"},
+		{"thumbnail", "cover_thumbnail.png"},
+		{"medium", "cover_medium.png"},
+	} {
+		name, err := RenditionName("cover.png", c.size)
+		if err != nil || name != c.expected {
+			t.Errorf("Expected '%s' for size '%s'. Got '%s', %v", c.expected, c.size, name, err)
+		}
+	}
+
+	if _, err := RenditionName("cover.png", "huge"); err != ErrUnknownSize {
+		t.Errorf("Expected ErrUnknownSize. Got %v", err)
+	}
+}
+
+// Test checking image sizes against the pixel limit.
+// Tests if sizes up to MaxPixels pass and larger ones fail without overflowing.
+func TestCheckPixels(t *testing.T) {
+	defer func(max int) { MaxPixels = max }(MaxPixels)
+	MaxPixels = 100
+
+	if err := CheckPixels(10, 10); err != nil {
+		t.Errorf("Expected 10x10 to pass. Got %v", err)
+	}
+	if err := CheckPixels(10, 11); err != ErrTooManyPixels {
+		t.Errorf("Expected ErrTooManyPixels for 10x11. Got %v", err)
+	}
+	if err := CheckPixels(1<<31-1, 1<<31-1); err != ErrTooManyPixels {
+		t.Errorf("Expected ErrTooManyPixels for the largest PNG size. Got %v", err)
+	}
+}
+
+// Test resizing images larger and smaller than the limit.
+// Tests if the longest side is scaled to the limit keeping the aspect ratio.
+func TestResize(t *testing.T) {
+	for _, c := range []struct {
+		width, height int
+		max           int
+		expected      image.Point
+	}{
+		{400, 200, 100, image.Pt(100, 50)},
+		{200, 400, 100, image.Pt(50, 100)},
+		{1000, 1, 100, image.Pt(100, 1)},
+		{80, 60, 100, image.Pt(80, 60)},
+	} {
+		src := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+		if size := Resize(src, c.max).Bounds().Size(); size != c.expected {
+			t.Errorf("Expected %v resizing %dx%d to %d. Got %v", c.expected, c.width, c.height, c.max, size)
+		}
+	}
+}
+
+// Test resizing an image of one colour.
+// Tests if the colour is kept by averaging.
+func TestResizeKeepsColour(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	red := color.RGBA{R: 255, A: 255}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			src.Set(x, y, red)
+		}
+	}
+
+	dst := Resize(src, 3)
+	if got := color.RGBAModel.Convert(dst.At(1, 1)); got != red {
+		t.Errorf("Expected %v. Got %v", red, got)
+	}
+}
@@ -1,9 +1,13 @@
 package mail
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
-	"os"
+	"net/textproto"
 	"strings"
 )
 
@@ -14,29 +18,86 @@ const (
 	PASSWORD    = ""
 )
 
+// Delivers a message over SMTP. Tests replace it to capture emails
+// instead of sending them.
+var SendMail = smtp.SendMail
+
 type Email struct {
-	to      []string "to"
-	subject   string "subject"
-	msg       string "msg"
+	to          []string
+	subject     string
+	msg         string
+	attachments []Attachment
+}
+
+// File sent along with an email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 func NewEmail(to []string, subject, msg string) *Email {
 	return &Email{to:to, subject: subject, msg: msg}
 }
+
+// Adds a file to the email.
+func (e *Email) Attach(filename, contentType string, data []byte) {
+	e.attachments = append(e.attachments, Attachment{Filename: filename, ContentType: contentType, Data: data})
+}
+
 func SendEmail(email *Email) error {
 	auth := smtp.PlainAuth("", USER, PASSWORD, HOST)
 	sendTo := email.to
 	addr := fmt.Sprintf("%s:%s", HOST, PORT)
 
 		for _, v := range sendTo {
-			str := strings.Replace("From: "+USER+"~To: "+v+"~Subject: "+email.subject+"~~", "~", "\r\n", -1) + email.msg
-			err := smtp.SendMail(addr, auth, USER,	[]string{v}, []byte(str))
+			str, err := email.message(v)
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				return err
+			}
+			if err := SendMail(addr, auth, USER,	[]string{v}, str); err != nil {
+				return err
 			}
 			fmt.Println("Successfully sent mail to all user in toList")
 		}
 	return nil
 }
 
+// Builds the message to one recipient. Emails with attachments are sent
+// as multipart/mixed with the text first.
+func (e *Email) message(to string) ([]byte, error) {
+	header := strings.Replace("From: "+USER+"~To: "+to+"~Subject: "+e.subject+"~", "~", "\r\n", -1)
+	if len(e.attachments) == 0 {
+		return []byte(header + "\r\n" + e.msg), nil
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(e.msg))
+	for _, a := range e.attachments {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Base64 lines are kept to 76 characters as MIME requires.
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	header += "MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=" + w.Boundary() + "\r\n\r\n"
+	return append([]byte(header), body.Bytes()...), nil
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// Test building an email without attachments.
+// Tests if the text follows the headers as a plain message.
+func TestMessage(t *testing.T) {
+	out, err := NewEmail([]string{"reader@example.com"}, "Loan receipt", "Hello").message("reader@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Expected a valid message. Got %v", err)
+	}
+	if to := msg.Header.Get("To"); to != "reader@example.com" {
+		t.Errorf("Expected To reader@example.com. Got %s", to)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "Loan receipt" {
+		t.Errorf("Expected Subject 'Loan receipt'. Got %s", subject)
+	}
+	if body, _ := ioutil.ReadAll(msg.Body); string(body) != "Hello" {
+		t.Errorf("Expected body 'Hello'. Got %q", body)
+	}
+}
+
+// Test building an email with an attachment.
+// Tests if the message is multipart/mixed with the text first and the
+// attachment base64 encoded in lines of at most 76 characters.
+func TestMessageWithAttachment(t *testing.T) {
+	data := bytes.Repeat([]byte("%PDF-1.4 receipt "), 20)
+	email := NewEmail([]string{"reader@example.com"}, "Loan receipt", "Your receipt is attached.")
+	email.Attach("loan.pdf", "application/pdf", data)
+	out, err := email.message("reader@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Expected a valid message. Got %v", err)
+	}
+	if version := msg.Header.Get("MIME-Version"); version != "1.0" {
+		t.Errorf("Expected MIME-Version 1.0. Got %s", version)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed. Got %s, %v", mediaType, err)
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	text, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(text); string(body) != "Your receipt is attached." {
+		t.Errorf("Expected the text first. Got %q", body)
+	}
+
+	attachment, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := attachment.FileName(); name != "loan.pdf" {
+		t.Errorf("Expected file name loan.pdf. Got %s", name)
+	}
+	if ct := attachment.Header.Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Expected application/pdf. Got %s", ct)
+	}
+	encoded, _ := ioutil.ReadAll(attachment)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("Expected base64 lines of at most 76 characters. Got %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Replace(string(encoded), "\r\n", "", -1))
+	if err != nil || !bytes.Equal(decoded, data) {
+		t.Errorf("Expected the attachment data back. Got %q, %v", decoded, err)
+	}
+
+	if _, err := parts.NextPart(); err == nil {
+		t.Error("Expected no more parts")
+	}
+}
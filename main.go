@@ -3,7 +3,7 @@ package main
 import (
 	"github.com/library/app"
 	"github.com/library/callAt"
-	"github.com/library/db"
+	"github.com/library/model"
 	"github.com/spf13/viper"
 	"log"
 	"os"
@@ -39,22 +39,26 @@ func main() {
 	a.Initialize()
 	if os.Getenv("PORT") == "" {
 		// Get port from config if no env variable.
-		a.Run(":" + viper.GetString("PORT"))
+		go a.Run(":" + viper.GetString("PORT"))
 	} else {
 		// Get port from env.
-		a.Run(":" + os.Getenv("PORT"))
+		go a.Run(":" + os.Getenv("PORT"))
 	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	ticker := time.NewTicker(time.Hour)
+	purgeTicker := time.NewTicker(24 * time.Hour)
+	imageTicker := time.NewTicker(time.Hour)
+	holdTicker := time.NewTicker(time.Hour)
+	readingRoomTicker := time.NewTicker(15 * time.Minute)
 	task := make(chan []string)
 
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
-				listEmail, err := callAt.CheckReturnDate(db.DB{})
+				listEmail, err := callAt.CheckReturnDate(a.DB())
 				if err != nil {
 					log.Printf("Can not check return data for issue acts (%s):%s", time.Now(), err)
 				}
@@ -67,8 +71,7 @@ func main() {
 	go func() {
 		for {
 			select {
-			case <-task:
-				listEmail := <-task
+			case listEmail := <-task:
 				for _, v := range listEmail{
 					callAt.Email([]string{v})
 				}
@@ -77,7 +80,74 @@ func main() {
 		}
 
 	}()
+	go func() {
+		for {
+			select {
+			case <-purgeTicker.C:
+				// Hard delete records soft deleted longer than the retention period.
+				retention := time.Duration(viper.GetInt("PURGE_RETENTION_DAYS")) * 24 * time.Hour
+				purged, err := model.PurgeDeleted(a.DB().Database, time.Now().Add(-retention))
+				if err != nil {
+					log.Printf("Can not purge deleted records (%s):%s", time.Now(), err)
+				}
+				if purged > 0 {
+					log.Printf("Purged %d deleted records", purged)
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-imageTicker.C:
+				// Remove uploads nothing refers to once the grace period is over.
+				grace := time.Duration(viper.GetInt("IMAGE_CLEANUP_GRACE_HOURS")) * time.Hour
+				removed, err := a.CleanupImages(time.Now().Add(-grace))
+				if err != nil {
+					log.Printf("Can not clean up images (%s):%s", time.Now(), err)
+				}
+				if removed > 0 {
+					log.Printf("Removed %d unreferenced images", removed)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-holdTicker.C:
+				// Pass copies not picked up in time to the next reader in the queue.
+				expired, err := model.ExpireReservations(a.DB().Database, time.Now())
+				if err != nil {
+					log.Printf("Can not expire reservations (%s):%s", time.Now(), err)
+				}
+				if expired > 0 {
+					log.Printf("Expired %d reservations", expired)
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-readingRoomTicker.C:
+				// Flag reading-room loans not returned by closing time.
+				flagged, err := model.FlagReadingRoomLoans(a.DB().Database, time.Now())
+				if err != nil {
+					log.Printf("Can not flag reading-room loans (%s):%s", time.Now(), err)
+				}
+				if flagged > 0 {
+					log.Printf("Flagged %d reading-room loans", flagged)
+				}
+			}
+		}
+	}()
 
 	<-quit
 	ticker.Stop()
+	purgeTicker.Stop()
+	imageTicker.Stop()
+	holdTicker.Stop()
+	readingRoomTicker.Stop()
 }
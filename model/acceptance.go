@@ -3,7 +3,6 @@ package model
 import (
 	"database/sql"
 	"github.com/pkg/errors"
-	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,120 +17,124 @@ type Acceptance struct {
 	Discount         float32   `json:"discount" validate:"required" sql:"discount"`
 	FinalCost        float32   `json:"finalCost" validate:"required" sql:"final_cost"`
 	Photo            string    `json:"photo" validate:"required" sql:"photo"`
+	IssueID          *uuid.UUID `json:"issueID" sql:"issue_id"`
+	DaysKept         int       `json:"daysKept" sql:"days_kept"`
+	LateDays         int       `json:"lateDays" sql:"late_days"`
+	LateFee          float32   `json:"lateFee" sql:"late_fee"`
+	Surcharge        float32   `json:"surcharge" sql:"surcharge"`
+	DiscountExplanation DiscountExplanation `json:"discountExplanation" sql:"discount_explanation"`
+	Damages          []Damage  `json:"damages"`
+	Photos           []string  `json:"photos"`
+	BranchID         *uuid.UUID `json:"branchID" sql:"branch_id"`
 	CreatedAt        time.Time `json:"createdAt" sql:"created_at"`
 	UpdatedAt        time.Time `json:"updatedAt" sql:"updated_at"`
 }
 
+var ErrNoOpenLoan = errors.New("reader has no open loan of the book")
+
 // Query operations
 
-// Gets a specific acceptance by id.
+// Gets a specific acceptance by id with its damages and photos.
 func (dt *Acceptance) GetAcceptance(db *sql.DB) error {
-	return db.QueryRow("SELECT user_id, book_id, book_condition, discount, final_cost, photo, created_at, updated_at FROM acceptance WHERE id=$1",
-		dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.BookCondition, &dt.Discount, &dt.FinalCost, &dt.Photo, &dt.CreatedAt, &dt.UpdatedAt)
+	if err := db.QueryRow("SELECT user_id, book_id, book_condition, discount, final_cost, photo, issue_id, days_kept, late_days, late_fee, surcharge, discount_explanation, branch_id, created_at, updated_at FROM acceptance WHERE id=$1 AND deleted_at IS NULL",
+		dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.BookCondition, &dt.Discount, &dt.FinalCost, &dt.Photo, &dt.IssueID, &dt.DaysKept, &dt.LateDays, &dt.LateFee, &dt.Surcharge, &dt.DiscountExplanation, &dt.BranchID, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+		return err
+	}
+	return dt.loadAssessment(db)
 }
 
-// Gets acceptances. Limit count and start position in db.
+// Gets acceptances with their damages and photos. Limit count and start
+// position in db.
 func GetAcceptances(db *sql.DB, field, sort string, limit, page int) ([]Acceptance, error) {
 
-	rows, err := db.Query(  "SELECT * FROM acceptance ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query(  "SELECT id, user_id, book_id, book_condition, discount, final_cost, photo, issue_id, days_kept, late_days, late_fee, surcharge, discount_explanation, branch_id, created_at, updated_at FROM acceptance WHERE deleted_at IS NULL ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
 		return nil, err
 	}
-	// Wait for query to execute then close the row.
-	defer rows.Close()
 
 	acceptance := []Acceptance{}
 
 	// Store query results into acceptance variable if no errors.
 	for rows.Next() {
 		var dt Acceptance
-		if err := rows.Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.BookCondition, &dt.Discount, &dt.FinalCost, &dt.Photo, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+		if err := rows.Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.BookCondition, &dt.Discount, &dt.FinalCost, &dt.Photo, &dt.IssueID, &dt.DaysKept, &dt.LateDays, &dt.LateFee, &dt.Surcharge, &dt.DiscountExplanation, &dt.BranchID, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			rows.Close()
 			return nil, err
 		}
 		acceptance = append(acceptance, dt)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	for i := range acceptance {
+		if err := acceptance[i].loadAssessment(db); err != nil {
+			return nil, err
+		}
+	}
 	return acceptance, nil
 }
 
 // CRUD operations
 
-// Create new acceptance and insert to database.
+// Create new acceptance for the oldest open loan of the book by the
+// reader. The final cost is worked out as on the return of that loan;
+// ErrNoOpenLoan is returned if the reader has no loan of the book.
 func (dt *Acceptance) CreateAcceptance(db *sql.DB) error {
-	if dt.BookCondition == "" {
-		return errors.New("bookCondition is required")
+	var issueID uuid.UUID
+	err := db.QueryRow(`SELECT id FROM issue
+		WHERE user_id=$1 AND book_id=$2 AND closed_at IS NULL AND deleted_at IS NULL
+		ORDER BY created_at LIMIT 1`, dt.UserID, dt.BookID).Scan(&issueID)
+	if err == sql.ErrNoRows {
+		return ErrNoOpenLoan
 	}
-	if dt.Photo == "" {
-		return errors.New("photo is required")
-	}
-	if dt.FinalCost == 0 {
-		return errors.New("cost cannot be zero")
-	}
-	// Scan db after creation if acceptance exists using new acceptance id.
-	timestamp := time.Now()
-	err := db.QueryRow(
-		"INSERT INTO acceptance(user_id, book_id, book_condition, discount, final_cost, photo, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, user_id, book_id, book_condition, discount, final_cost, photo, created_at, updated_at", dt.UserID, dt.BookID, dt.BookCondition, dt.Discount, dt.FinalCost, dt.Photo, timestamp, timestamp).Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.BookCondition, &dt.Discount, &dt.FinalCost, &dt.Photo, &dt.CreatedAt, &dt.UpdatedAt)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return dt.ReturnIssue(db, issueID)
 }
 
-// Updates a specific acceptance details by id.
+// Updates the condition and photos of a specific acceptance by id. The
+// reader, book, discount and final cost stay as worked out on return.
+// Damages found on return are kept; photos may keep the file names stored
+// before uploads had IDs.
 func (dt *Acceptance) UpdateAcceptance(db *sql.DB) error {
-	if dt.BookCondition == "" {
-		return errors.New("bookCondition is required")
-	}
-	if dt.Photo == "" {
-		return errors.New("photo is required")
-	}
-	if dt.FinalCost == 0 {
-		return errors.New("cost cannot be zero")
+	if dt.UserID != uuid.Nil || dt.BookID != uuid.Nil || dt.Discount != 0 || dt.FinalCost != 0 {
+		return ErrClientCost
 	}
 	timestamp := time.Now()
-	_, err :=
-		db.Exec("UPDATE acceptance SET user_id=$1, book_id=$2, book_condition=$3, discount=$4, final_cost=$5, photo=$6, updated_at=$7 WHERE id=$8 RETURNING id, user_id, book_id, book_condition, discount, final_cost, photo, created_at, updated_at",  dt.UserID, dt.BookID, dt.BookCondition, dt.Discount, dt.FinalCost, dt.Photo, timestamp, dt.ID)
-
-	return err
+	return withTx(db, func(tx *sql.Tx) error {
+		var current string
+		if err := tx.QueryRow("SELECT photo FROM acceptance WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", dt.ID).Scan(&current); err != nil {
+			return err
+		}
+		photos, err := acceptancePhotos(tx, dt.ID)
+		if err != nil {
+			return err
+		}
+		if dt.Damages, err = acceptanceDamages(tx, dt.ID); err != nil {
+			return err
+		}
+		if err := dt.assess(tx, append(photos, current)...); err != nil {
+			return err
+		}
+		if err := tx.QueryRow("UPDATE acceptance SET book_condition=$1, photo=$2, updated_at=$3 WHERE id=$4 RETURNING user_id, book_id, discount, final_cost, issue_id, days_kept, late_days, late_fee, surcharge, discount_explanation, branch_id, created_at, updated_at",
+			dt.BookCondition, dt.Photo, timestamp, dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.Discount, &dt.FinalCost, &dt.IssueID, &dt.DaysKept, &dt.LateDays, &dt.LateFee, &dt.Surcharge, &dt.DiscountExplanation, &dt.BranchID, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return err
+		}
+		return dt.savePhotos(tx)
+	})
 }
 
-// Deletes a specific acceptance by id.
+// Soft deletes a specific acceptance by id.
 func (dt *Acceptance) DeleteAcceptance(db *sql.DB) error {
-	_, err := db.Exec("DELETE FROM acceptance WHERE id=$1", dt.ID)
-
-	return err
+	return softDelete(db, "acceptance", dt.ID)
 }
 
-func GetProfit(db *sql.DB) (float32, error) {
-	var profit float32
-	rows, err := db.Query(
-		"SELECT SUM (final_cost) FROM acceptance")
-	if err != nil {
-		log.Fatalf("Can not executes a query:%s", err)
-	}
-	// Wait for query to execute then close the row.
-	defer rows.Close()
-	// Store query results into acceptance variable if no errors.
-	for rows.Next() {
-		var profit float32
-		if err2 := rows.Scan(&profit); err2 != nil {
-			log.Fatalf("Scan error:%s", err2)
-		}
-		return profit, nil
-	}
-	return profit, nil
-}
-
-func (dt *Acceptance) DiscountFunc(a *Books) {
-	if a.NumberOfBooks > 2 {
-		dt.Discount = 0.10
-		dt.FinalCost = dt.FinalCost * dt.Discount
-	}
-	if a.NumberOfBooks > 4 {
-		dt.Discount = 0.15
-		dt.FinalCost = dt.FinalCost * dt.Discount
-	}
+// Restores a soft deleted acceptance by id.
+func (dt *Acceptance) RestoreAcceptance(db *sql.DB) error {
+	return restore(db, "acceptance", dt.ID)
 }
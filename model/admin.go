@@ -11,19 +11,21 @@ import (
 
 // Defines admin model.
 type Admin struct {
-	ID        uuid.UUID `json:"id" sql:"uuid"`
-	Email     string    `json:"email" validate:"required" sql:"email"`
-	Password  string    `json:"password" validate:"required" sql:"password"`
-	CreatedAt time.Time `json:"createdAt" sql:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" sql:"updated_at"`
+	ID         uuid.UUID `json:"id" sql:"uuid"`
+	Email      string    `json:"email" validate:"required" sql:"email"`
+	Password   string    `json:"password" validate:"required" sql:"password"`
+	// Privileged admins may override lending blocks.
+	Privileged bool      `json:"privileged" sql:"privileged"`
+	CreatedAt  time.Time `json:"createdAt" sql:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" sql:"updated_at"`
 }
 
 // Query operations
 
 // Gets a specific admin by id.
 func (u *Admin) GetAdmin(db *sql.DB) error {
-	return db.QueryRow("SELECT email, created_at, updated_at FROM admins WHERE id=$1",
-		u.ID).Scan(&u.Email, &u.CreatedAt, &u.UpdatedAt)
+	return db.QueryRow("SELECT email, privileged, created_at, updated_at FROM admins WHERE id=$1",
+		u.ID).Scan(&u.Email, &u.Privileged, &u.CreatedAt, &u.UpdatedAt)
 }
 
 // Gets a specific admin by email and password.
@@ -34,7 +36,7 @@ func (u *Admin) GetAdminByEmailAndPassword(db *sql.DB) error {
 // Gets multiple admin. Limit count and start position in db.
 func GetAdmins(db *sql.DB, field, sort string, limit, page int) ([]Admin, error) {
 
-	rows, err := db.Query( "SELECT id, email, created_at, updated_at FROM admins ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query( "SELECT id, email, privileged, created_at, updated_at FROM admins ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
@@ -48,7 +50,7 @@ func GetAdmins(db *sql.DB, field, sort string, limit, page int) ([]Admin, error)
 	// Store query results into admin variable if no errors.
 	for rows.Next() {
 		var u Admin
-		if err := rows.Scan(&u.ID, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.Privileged, &u.CreatedAt, &u.UpdatedAt); err != nil {
 			return nil, err
 		}
 		admins = append(admins, u)
@@ -106,6 +108,40 @@ func (u *Admin) UpdateAdmin(db *sql.DB) error {
 	return err
 }
 
+// Grants or revokes the privileged role of an admin by id.
+func (u *Admin) SetPrivileged(db *sql.DB) error {
+	res, err := db.Exec("UPDATE admins SET privileged=$1, updated_at=$2 WHERE id=$3", u.Privileged, time.Now(), u.ID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// Grants the privileged role to the admins with the given emails. Returns
+// the emails no admin has.
+func GrantPrivileged(db *sql.DB, emails []string) ([]string, error) {
+	unknown := []string{}
+	for _, email := range emails {
+		res, err := db.Exec("UPDATE admins SET privileged=true, updated_at=$1 WHERE email=$2 AND NOT privileged", time.Now(), email)
+		if err != nil {
+			return nil, err
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if affected > 0 {
+			continue
+		}
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM admins WHERE email=$1)", email).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			unknown = append(unknown, email)
+		}
+	}
+	return unknown, nil
+}
+
 // Deletes a specific admin by id.
 func (u *Admin) DeleteAdmin(db *sql.DB) error {
 	_, err := db.Exec("DELETE FROM admins WHERE id=$1", u.ID)
@@ -25,7 +25,7 @@ type Author struct {
 // Gets authors. Limit count and start position in db.
 func GetAuthors(db *sql.DB, field, sort string, limit, page int) ([]Author, error) {
 
-	rows, err := db.Query(  "SELECT * FROM authors ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query(  "SELECT id, firstname, surname, date_of_birth, photo, created_at, updated_at FROM authors WHERE deleted_at IS NULL ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
@@ -64,6 +64,9 @@ func (dt *Author) CreateAuthor(db *sql.DB) error {
 	if dt.Photo == "" {
 		return errors.New("photo is required")
 	}
+	if err := validateImage(db, &dt.Photo); err != nil {
+		return err
+	}
 	// Scan db after creation if author exists using new author id.
 	timestamp := time.Now()
 	err := db.QueryRow(
@@ -75,8 +78,8 @@ func (dt *Author) CreateAuthor(db *sql.DB) error {
 	return nil
 }
 
-// Updates a specific author details by id.
-func (dt *Author) UpdateAuthor(db *sql.DB) error {
+// Updates a specific author details by id and stores the change in history.
+func (dt *Author) UpdateAuthor(db *sql.DB, changedBy string) error {
 	if dt.Firstname == "" {
 		return errors.New("name is required")
 	}
@@ -90,8 +93,49 @@ func (dt *Author) UpdateAuthor(db *sql.DB) error {
 		return errors.New("photo is required")
 	}
 	timestamp := time.Now()
-	_, err :=
-		db.Exec("UPDATE authors SET firstname=$1, surname=$2, date_of_birth=$3, photo=$4, updated_at=$5 WHERE id=$6 RETURNING id, firstname, surname, date_of_birth, photo, created_at, updated_at", dt.Firstname, dt.Surname,  dt.DateOfBirth, dt.Photo, timestamp, dt.ID)
+	return withTx(db, func(tx *sql.Tx) error {
+		// Lock current state so it can be stored in change history.
+		previous := Author{ID: dt.ID}
+		if err := tx.QueryRow("SELECT firstname, surname, date_of_birth, photo FROM authors WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&previous.Firstname, &previous.Surname, &previous.DateOfBirth, &previous.Photo); err != nil {
+			return err
+		}
+		if err := validateImage(tx, &dt.Photo, previous.Photo); err != nil {
+			return err
+		}
+		_, err :=
+			tx.Exec("UPDATE authors SET firstname=$1, surname=$2, date_of_birth=$3, photo=$4, updated_at=$5 WHERE id=$6", dt.Firstname, dt.Surname, dt.DateOfBirth, dt.Photo, timestamp, dt.ID)
+		if err != nil {
+			return err
+		}
+		return recordVersion(tx, VersionAuthor, dt.ID, previous.versionData(), dt.versionData(), changedBy)
+	})
+}
+
+// Reverts an author to the state stored in a previous version.
+func (dt *Author) RevertAuthor(db *sql.DB, version int, changedBy string) error {
+	if err := loadVersion(db, VersionAuthor, dt.ID, version, dt); err != nil {
+		return err
+	}
+	return dt.UpdateAuthor(db, changedBy)
+}
+
+// Fields of an author tracked in change history.
+func (dt *Author) versionData() map[string]interface{} {
+	return map[string]interface{}{
+		"firstname":   dt.Firstname,
+		"surname":     dt.Surname,
+		"dateOfBirth": dt.DateOfBirth,
+		"photo":       dt.Photo,
+	}
+}
+
+// Soft deletes a specific author by id.
+func (dt *Author) DeleteAuthor(db *sql.DB) error {
+	return softDelete(db, "authors", dt.ID)
+}
 
-	return err
+// Restores a soft deleted author by id.
+func (dt *Author) RestoreAuthor(db *sql.DB) error {
+	return restore(db, "authors", dt.ID)
 }
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Balance above which a reader can't borrow books.
+var MaxDebt float32 = 10
+
+var (
+	ErrNotPrivileged  = errors.New("only privileged admins can override a lending block")
+	ErrOverrideReason = errors.New("override reason is required")
+)
+
+// Returned when a reader is blocked from borrowing. Lists every reason
+// together with the overdue loans and balance behind it.
+type LendingBlockError struct {
+	Reasons       []PolicyViolation `json:"reasons"`
+	OverdueIssues []uuid.UUID       `json:"overdueIssues"`
+	Balance       float32           `json:"balance"`
+	DebtLimit     float32           `json:"debtLimit"`
+}
+
+func (e *LendingBlockError) Error() string {
+	messages := make([]string, len(e.Reasons))
+	for i, v := range e.Reasons {
+		messages[i] = v.Message
+	}
+	return "reader is blocked from borrowing: " + strings.Join(messages, "; ")
+}
+
+// Defines lending override model: a privileged admin letting a blocked
+// reader borrow a book anyway.
+type LendingOverride struct {
+	ID        uuid.UUID          `json:"id"       sql:"uuid"`
+	IssueID   uuid.UUID          `json:"issueID" sql:"issue_id"`
+	UserID    uuid.UUID          `json:"userID" sql:"user_id"`
+	AdminID   uuid.UUID          `json:"adminID" sql:"admin_id"`
+	Reason    string             `json:"reason" validate:"required" sql:"reason"`
+	Block     *LendingBlockError `json:"block" sql:"block"`
+	CreatedAt time.Time          `json:"createdAt" sql:"created_at"`
+}
+
+// Query operations
+
+// Returns why a reader can't borrow books, or nil if they can.
+func lendingBlock(q queryer, userID uuid.UUID, now time.Time) (*LendingBlockError, error) {
+	block := &LendingBlockError{Reasons: []PolicyViolation{}, OverdueIssues: []uuid.UUID{}, DebtLimit: MaxDebt}
+	rows, err := q.Query("SELECT id FROM issue WHERE user_id=$1 AND closed_at IS NULL AND deleted_at IS NULL AND return_date < $2::date ORDER BY return_date",
+		userID, now.Format(DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		block.OverdueIssues = append(block.OverdueIssues, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if n := len(block.OverdueIssues); n > 0 {
+		block.Reasons = append(block.Reasons, PolicyViolation{"overdue_loans",
+			fmt.Sprintf("reader has %d overdue loans", n)})
+	}
+	if block.Balance, err = balanceOf(q, userID); err != nil {
+		return nil, err
+	}
+	if block.Balance > MaxDebt {
+		block.Reasons = append(block.Reasons, PolicyViolation{"debt_limit",
+			fmt.Sprintf("reader owes %.2f, more than the limit of %.2f", block.Balance, MaxDebt)})
+	}
+	if len(block.Reasons) == 0 {
+		return nil, nil
+	}
+	return block, nil
+}
+
+// Gets the lending block of a reader, nil if they may borrow.
+func GetLendingBlock(db *sql.DB, userID uuid.UUID) (*LendingBlockError, error) {
+	if err := checkUser(db, userID); err != nil {
+		return nil, err
+	}
+	return lendingBlock(db, userID, time.Now())
+}
+
+// Gets recorded overrides, newest first. A nil userID lists all readers.
+func GetLendingOverrides(db *sql.DB, userID *uuid.UUID) ([]LendingOverride, error) {
+	rows, err := db.Query("SELECT id, issue_id, user_id, admin_id, reason, block, created_at FROM lending_overrides WHERE $1::uuid IS NULL OR user_id=$1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	overrides := []LendingOverride{}
+
+	// Store query results into overrides variable if no errors.
+	for rows.Next() {
+		var dt LendingOverride
+		var block []byte
+		if err := rows.Scan(&dt.ID, &dt.IssueID, &dt.UserID, &dt.AdminID, &dt.Reason, &block, &dt.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(block, &dt.Block); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, dt)
+	}
+
+	return overrides, rows.Err()
+}
+
+// CRUD operations
+
+// Checks whether the reader of an issue is blocked from borrowing. Readers
+// with overdue loans or too much debt are blocked unless a privileged admin
+// overrides it; the block is returned so the override can be recorded.
+func (dt *Issue) checkLendingBlock(q queryer, now time.Time) (*LendingBlockError, error) {
+	block, err := lendingBlock(q, dt.UserID, now)
+	if err != nil {
+		return nil, err
+	}
+	if block != nil && dt.Override == nil {
+		return nil, block
+	}
+	if block == nil {
+		dt.Override = nil
+	} else if err := dt.Override.validate(q); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Checks that the override comes from a privileged admin.
+func (dt *LendingOverride) validate(q queryer) error {
+	if dt.Reason == "" {
+		return ErrOverrideReason
+	}
+	var privileged bool
+	err := q.QueryRow("SELECT privileged FROM admins WHERE id=$1", dt.AdminID).Scan(&privileged)
+	if err == sql.ErrNoRows || err == nil && !privileged {
+		return ErrNotPrivileged
+	}
+	return err
+}
+
+// Records an override of block for an issue made at the given time.
+func (dt *LendingOverride) record(q queryer, issue *Issue, block *LendingBlockError, at time.Time) error {
+	payload, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	dt.IssueID, dt.UserID, dt.Block = issue.ID, issue.UserID, block
+	return q.QueryRow("INSERT INTO lending_overrides(issue_id, user_id, admin_id, reason, block, created_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		dt.IssueID, dt.UserID, dt.AdminID, dt.Reason, payload, at).Scan(&dt.ID, &dt.CreatedAt)
+}
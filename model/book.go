@@ -30,6 +30,7 @@ type Book struct {
 	YearOfPublishing uint      `json:"yearOfPublishing" validate:"required" sql:"year_of_publishing"`
 	NumberOfPages    uint      `json:"numberOfPages" validate:"required" sql:"number_of_pages"`
 	Views            uint      `json:"views" validate:"required" sql:"views"`
+	ReadingRoomOnly  bool      `json:"readingRoomOnly" sql:"reading_room_only"`
 	CreatedAt        time.Time `json:"createdAt" sql:"created_at"`
 	UpdatedAt        time.Time `json:"updatedAt" sql:"updated_at"`
 }
@@ -38,14 +39,14 @@ type Book struct {
 
 // Gets a specific book by name.
 func (dt *Book) GetBook(db *sql.DB) error {
-	return db.QueryRow("SELECT id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, created_at, updated_at FROM book WHERE name=$1",
-		dt.Name).Scan(&dt.ID, &dt.Name, &dt.Cost, &dt.PricePerDay, &dt.Photo, &dt.YearOfPublishing, &dt.NumberOfPages, &dt.Views, &dt.CreatedAt, &dt.UpdatedAt)
+	return db.QueryRow("SELECT id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, reading_room_only, created_at, updated_at FROM book WHERE name=$1 AND deleted_at IS NULL",
+		dt.Name).Scan(&dt.ID, &dt.Name, &dt.Cost, &dt.PricePerDay, &dt.Photo, &dt.YearOfPublishing, &dt.NumberOfPages, &dt.Views, &dt.ReadingRoomOnly, &dt.CreatedAt, &dt.UpdatedAt)
 }
 
 // Gets books. Limit count and start position in db.
 func GetBooks(db *sql.DB, field, sort string, limit, page int) ([]Book, error) {
 
-	rows, err := db.Query("SELECT * FROM book ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query("SELECT id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, reading_room_only, created_at, updated_at FROM book WHERE deleted_at IS NULL ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
@@ -57,7 +58,7 @@ func GetBooks(db *sql.DB, field, sort string, limit, page int) ([]Book, error) {
 	// Store query results into book variable if no errors.
 	for rows.Next() {
 		var dt Book
-		if err := rows.Scan(&dt.ID, &dt.Name, &dt.Cost, &dt.PricePerDay, &dt.Photo, &dt.YearOfPublishing, &dt.NumberOfPages, &dt.Views, &dt.CreatedAt, &dt.UpdatedAt);
+		if err := rows.Scan(&dt.ID, &dt.Name, &dt.Cost, &dt.PricePerDay, &dt.Photo, &dt.YearOfPublishing, &dt.NumberOfPages, &dt.Views, &dt.ReadingRoomOnly, &dt.CreatedAt, &dt.UpdatedAt);
 		err != nil {
 			return nil, err
 		}
@@ -71,7 +72,9 @@ func GetBooks(db *sql.DB, field, sort string, limit, page int) ([]Book, error) {
 
 // CRUD operations
 
-// Create new book and insert to database.
+// Create new book and insert to database. Copies are put on the shelves
+// of the oldest branch, so ErrBranchNotFound is returned when copies are
+// given and there is no branch.
 func (dt *Book) CreateBook(db *sql.DB, categoryId, authorId string, boosNumber int) error {
 	if dt.Name == "" {
 		return errors.New("name is required")
@@ -85,17 +88,25 @@ func (dt *Book) CreateBook(db *sql.DB, categoryId, authorId string, boosNumber i
 	if dt.Photo == "" {
 		return errors.New("photo is required")
 	}
+	if err := validateImage(db, &dt.Photo); err != nil {
+		return err
+	}
 	if dt.YearOfPublishing == 0 {
 		return errors.New("yearOfPublishing cannot be zero")
 	}
 	if dt.NumberOfPages == 0 {
 		return errors.New("numberOfPages cannot be zero")
 	}
+	if boosNumber != 0 {
+		if _, err := resolveBranch(db, nil); err != nil {
+			return err
+		}
+	}
 	// Scan db after creation if book exists using new book id.
 
 	timestamp := time.Now()
 	err := db.QueryRow(
-		"INSERT INTO book(name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, created_at, updated_at", dt.Name, dt.Cost, dt.PricePerDay, dt.Photo, dt.YearOfPublishing, dt.NumberOfPages, dt.Views, timestamp, timestamp).Scan(&dt.ID, &dt.Name, &dt.Cost, &dt.PricePerDay, &dt.Photo, &dt.YearOfPublishing, &dt.NumberOfPages, &dt.Views, &dt.CreatedAt, &dt.UpdatedAt)
+		"INSERT INTO book(name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, reading_room_only, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, reading_room_only, created_at, updated_at", dt.Name, dt.Cost, dt.PricePerDay, dt.Photo, dt.YearOfPublishing, dt.NumberOfPages, dt.Views, dt.ReadingRoomOnly, timestamp, timestamp).Scan(&dt.ID, &dt.Name, &dt.Cost, &dt.PricePerDay, &dt.Photo, &dt.YearOfPublishing, &dt.NumberOfPages, &dt.Views, &dt.ReadingRoomOnly, &dt.CreatedAt, &dt.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -109,7 +120,7 @@ func (dt *Book) CreateBook(db *sql.DB, categoryId, authorId string, boosNumber i
 		createAuthors(db, dt.ID, authorID)
 	}
 	if boosNumber != 0{
-		createBooks(db, dt.ID, boosNumber)
+		return createBooks(db, dt.ID, boosNumber)
 	}
 
 	return nil
@@ -131,18 +142,24 @@ func createCategory(db *sql.DB ,id, categoryId uuid.UUID) error{
 	}
 	return nil
 }
+// Puts copies of a new book on the shelves of the oldest branch.
 func createBooks(db *sql.DB ,id uuid.UUID, booksNumber int) error{
 	timestamp := time.Now()
-	err2 := db.QueryRow(
-		"INSERT INTO books(book_id, number_of_book, created_at, deleted_at ) VALUES($1, $2, $3, $4)", id, booksNumber, timestamp, timestamp).Scan(&id, &booksNumber, &timestamp, &timestamp)
-	if err2 != nil {
-		return nil
+	res, err := db.Exec(
+		"INSERT INTO books(book_id, branch_id, number_of_book, created_at, deleted_at ) SELECT $1, id, $2, $3, $4 FROM branches WHERE deleted_at IS NULL ORDER BY created_at LIMIT 1", id, booksNumber, timestamp, timestamp)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(res); err == sql.ErrNoRows {
+		return ErrBranchNotFound
+	} else if err != nil {
+		return err
 	}
 	return nil
 }
 
-// Updates a specific book details by id.
-func (dt *Book) UpdateBook(db *sql.DB) error {
+// Updates a specific book details by id and stores the change in history.
+func (dt *Book) UpdateBook(db *sql.DB, changedBy string) error {
 	if dt.Name == "" {
 		return errors.New("name is required")
 	}
@@ -162,17 +179,55 @@ func (dt *Book) UpdateBook(db *sql.DB) error {
 		return errors.New("numberOfPages cannot be zero")
 	}
 	timestamp := time.Now()
-	_, err :=
-		db.Exec("UPDATE book SET name=$1, cost=$2, price_per_day=$3, photo=$4, year_of_publishing=$5, number_of_pages=$6, views=$7, updated_at=$8 WHERE id=$9 RETURNING id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, created_at, updated_at", dt.Name, dt.Cost, dt.PricePerDay, dt.Photo, dt.YearOfPublishing, dt.NumberOfPages, dt.Views, timestamp, dt.ID)
+	return withTx(db, func(tx *sql.Tx) error {
+		// Lock current state so it can be stored in change history.
+		previous := Book{ID: dt.ID}
+		if err := tx.QueryRow("SELECT name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, reading_room_only FROM book WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&previous.Name, &previous.Cost, &previous.PricePerDay, &previous.Photo, &previous.YearOfPublishing, &previous.NumberOfPages, &previous.Views, &previous.ReadingRoomOnly); err != nil {
+			return err
+		}
+		if err := validateImage(tx, &dt.Photo, previous.Photo); err != nil {
+			return err
+		}
+		_, err :=
+			tx.Exec("UPDATE book SET name=$1, cost=$2, price_per_day=$3, photo=$4, year_of_publishing=$5, number_of_pages=$6, views=$7, reading_room_only=$8, updated_at=$9 WHERE id=$10", dt.Name, dt.Cost, dt.PricePerDay, dt.Photo, dt.YearOfPublishing, dt.NumberOfPages, dt.Views, dt.ReadingRoomOnly, timestamp, dt.ID)
+		if err != nil {
+			return err
+		}
+		return recordVersion(tx, VersionBook, dt.ID, previous.versionData(), dt.versionData(), changedBy)
+	})
+}
+
+// Reverts a book to the state stored in a previous version.
+func (dt *Book) RevertBook(db *sql.DB, version int, changedBy string) error {
+	if err := loadVersion(db, VersionBook, dt.ID, version, dt); err != nil {
+		return err
+	}
+	return dt.UpdateBook(db, changedBy)
+}
 
-	return err
+// Fields of a book tracked in change history.
+func (dt *Book) versionData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":             dt.Name,
+		"cost":             dt.Cost,
+		"pricePerDay":      dt.PricePerDay,
+		"photo":            dt.Photo,
+		"yearOfPublishing": dt.YearOfPublishing,
+		"numberOfPages":    dt.NumberOfPages,
+		"views":            dt.Views,
+		"readingRoomOnly":  dt.ReadingRoomOnly,
+	}
 }
 
-// Deletes a specific book by id.
+// Soft deletes a specific book by id.
 func (dt *Book) DeleteBook(db *sql.DB) error {
-	_, err := db.Exec("DELETE FROM book WHERE id=$1", dt.ID)
+	return softDelete(db, "book", dt.ID)
+}
 
-	return err
+// Restores a soft deleted book by id.
+func (dt *Book) RestoreBook(db *sql.DB) error {
+	return restore(db, "book", dt.ID)
 }
 
 
@@ -219,7 +274,7 @@ func SelectCategories(db *sql.DB ,id uuid.UUID) []Categories {
 
 
 func SelectAuthors(db *sql.DB ,id uuid.UUID) []Author {
-	get, err := db.Query("SELECT id, firstname, surname, date_of_birth, photo, created_at, updated_at FROM authors JOIN book_authors ON authors.id = book_authors.author_id AND book_authors.book_id = $1", id)
+	get, err := db.Query("SELECT id, firstname, surname, date_of_birth, photo, created_at, updated_at FROM authors JOIN book_authors ON authors.id = book_authors.author_id AND book_authors.book_id = $1 WHERE authors.deleted_at IS NULL", id)
 	if err != nil{
 		return nil
 	}
@@ -8,10 +8,11 @@ import (
 	"github.com/google/uuid"
 )
 
-// Defines book model.
+// Defines book model: copies of a book on the shelves of a branch.
 type Books struct {
 	ID               uuid.UUID `json:"id"       sql:"uuid"`
 	BookID           uuid.UUID `json:"bookID" validate:"required" sql:"book_id"`
+	BranchID         uuid.UUID `json:"branchID" validate:"required" sql:"branch_id"`
 	NumberOfBooks    uint      `json:"numberOfBooks" validate:"required" sql:"number_of_books"`
 	CreatedAt        time.Time `json:"createdAt" sql:"created_at"`
 	DeletedAt        time.Time `json:"deletedAt" sql:"deleted_at"`
@@ -21,14 +22,14 @@ type Books struct {
 
 // Gets a specific book by id.
 func (dt *Books) GetNumberBook(db *sql.DB) error {
-	return db.QueryRow("SELECT book_id, number_of_book, created_at, deleted_at FROM books WHERE id=$1",
-		dt.ID).Scan(&dt.BookID, &dt.NumberOfBooks,  &dt.CreatedAt, &dt.DeletedAt)
+	return db.QueryRow("SELECT book_id, branch_id, number_of_book, created_at, deleted_at FROM books WHERE id=$1",
+		dt.ID).Scan(&dt.BookID, &dt.BranchID, &dt.NumberOfBooks,  &dt.CreatedAt, &dt.DeletedAt)
 }
 
 // Gets books. Limit count and start position in db.
 func GetNumberBooks(db *sql.DB, field, sort string, limit, page int) ([]Books, error) {
 
-	rows, err := db.Query(  "SELECT * FROM books ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query(  "SELECT id, book_id, branch_id, number_of_book, created_at, deleted_at FROM books ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
@@ -42,7 +43,7 @@ func GetNumberBooks(db *sql.DB, field, sort string, limit, page int) ([]Books, e
 	// Store query results into book variable if no errors.
 	for rows.Next() {
 		var dt Books
-		if err := rows.Scan(&dt.ID, &dt.BookID, &dt.NumberOfBooks, &dt.CreatedAt, &dt.DeletedAt); err != nil {
+		if err := rows.Scan(&dt.ID, &dt.BookID, &dt.BranchID, &dt.NumberOfBooks, &dt.CreatedAt, &dt.DeletedAt); err != nil {
 			return nil, err
 		}
 		book = append(book, dt)
@@ -58,10 +59,13 @@ func (dt *Books) CreateNumberBook(db *sql.DB) error {
 	if dt.NumberOfBooks == 0 {
 		return errors.New("books cannot be zero")
 	}
+	if _, err := resolveBranch(db, &dt.BranchID); err != nil {
+		return err
+	}
 	// Scan db after creation if book exists using new book id.
 	timestamp := time.Now()
 	err := db.QueryRow(
-		"INSERT INTO books(book_id, number_of_book, created_at, deleted_at) VALUES($1, $2, $3, $4) RETURNING id, book_id, number_of_book, created_at, deleted_at", dt.BookID, dt.NumberOfBooks, timestamp, timestamp).Scan(&dt.ID, &dt.BookID, &dt.NumberOfBooks, &dt.CreatedAt, &dt.DeletedAt)
+		"INSERT INTO books(book_id, branch_id, number_of_book, created_at, deleted_at) VALUES($1, $2, $3, $4, $5) RETURNING id, book_id, branch_id, number_of_book, created_at, deleted_at", dt.BookID, dt.BranchID, dt.NumberOfBooks, timestamp, timestamp).Scan(&dt.ID, &dt.BookID, &dt.BranchID, &dt.NumberOfBooks, &dt.CreatedAt, &dt.DeletedAt)
 	if err != nil {
 		return err
 	}
@@ -69,16 +73,42 @@ func (dt *Books) CreateNumberBook(db *sql.DB) error {
 	return nil
 }
 
-// Updates a specific book details by id.
-func (dt *Books) UpdateNumberBook(db *sql.DB) error {
+// Updates a specific book details by id and stores the change in history.
+func (dt *Books) UpdateNumberBook(db *sql.DB, changedBy string) error {
 	if dt.NumberOfBooks == 0 {
 		return errors.New("books cannot be zero")
 	}
 	timestamp := time.Now()
-	_, err :=
-		db.Exec("UPDATE books SET book_id=$1, number_of_book=$2, deleted_at=$3 WHERE id=$4 RETURNING id,  book_id, number_of_book, created_at, deleted_at",dt.BookID, dt.NumberOfBooks, timestamp, dt.ID)
+	return withTx(db, func(tx *sql.Tx) error {
+		// Lock current state so it can be stored in change history.
+		previous := Books{ID: dt.ID}
+		if err := tx.QueryRow("SELECT book_id, number_of_book FROM books WHERE id=$1 FOR UPDATE",
+			dt.ID).Scan(&previous.BookID, &previous.NumberOfBooks); err != nil {
+			return err
+		}
+		_, err :=
+			tx.Exec("UPDATE books SET book_id=$1, number_of_book=$2, deleted_at=$3 WHERE id=$4", dt.BookID, dt.NumberOfBooks, timestamp, dt.ID)
+		if err != nil {
+			return err
+		}
+		return recordVersion(tx, VersionBooks, dt.ID, previous.versionData(), dt.versionData(), changedBy)
+	})
+}
 
-	return err
+// Reverts a book count to the state stored in a previous version.
+func (dt *Books) RevertNumberBook(db *sql.DB, version int, changedBy string) error {
+	if err := loadVersion(db, VersionBooks, dt.ID, version, dt); err != nil {
+		return err
+	}
+	return dt.UpdateNumberBook(db, changedBy)
+}
+
+// Fields of a book count tracked in change history.
+func (dt *Books) versionData() map[string]interface{} {
+	return map[string]interface{}{
+		"bookID":        dt.BookID,
+		"numberOfBooks": dt.NumberOfBooks,
+	}
 }
 
 // Deletes a specific book by id.
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBranchNotFound = errors.New("branch not found")
+	ErrBranchNotEmpty = errors.New("branch still has copies on its shelves, in transit, on loan or on hold")
+)
+
+// Defines branch model.
+type Branch struct {
+	ID        uuid.UUID `json:"id"       sql:"uuid"`
+	Name      string    `json:"name" validate:"required" sql:"name"`
+	Address   string    `json:"address" sql:"address"`
+	CreatedAt time.Time `json:"createdAt" sql:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" sql:"updated_at"`
+}
+
+// Copies of a book at a branch. InTransit counts copies shipped to the
+// branch that have not arrived yet.
+type BranchStock struct {
+	BranchID   uuid.UUID `json:"branchID"`
+	BranchName string    `json:"branchName"`
+	Available  int       `json:"available"`
+	InTransit  int       `json:"inTransit"`
+}
+
+// Query operations
+
+// Gets a specific branch by id.
+func (dt *Branch) GetBranch(db *sql.DB) error {
+	return db.QueryRow("SELECT name, address, created_at, updated_at FROM branches WHERE id=$1 AND deleted_at IS NULL",
+		dt.ID).Scan(&dt.Name, &dt.Address, &dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Gets branches ordered by name.
+func GetBranches(db *sql.DB) ([]Branch, error) {
+	rows, err := db.Query("SELECT id, name, address, created_at, updated_at FROM branches WHERE deleted_at IS NULL ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	branches := []Branch{}
+
+	// Store query results into branches variable if no errors.
+	for rows.Next() {
+		var dt Branch
+		if err := rows.Scan(&dt.ID, &dt.Name, &dt.Address, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return nil, err
+		}
+		branches = append(branches, dt)
+	}
+
+	return branches, rows.Err()
+}
+
+// Gets copies of a book per branch, including branches it is in transit to.
+func GetBookStock(db *sql.DB, bookID uuid.UUID) ([]BranchStock, error) {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM book WHERE id=$1 AND deleted_at IS NULL)", bookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrBookNotFound
+	}
+	rows, err := db.Query(`SELECT br.id, br.name,
+			COALESCE((SELECT SUM(number_of_book) FROM books WHERE book_id=$1 AND branch_id=br.id), 0),
+			COALESCE((SELECT SUM(quantity) FROM transfers WHERE book_id=$1 AND to_branch_id=br.id AND status=$2), 0)
+		FROM branches br
+		WHERE br.id IN (SELECT branch_id FROM books WHERE book_id=$1)
+			OR br.id IN (SELECT to_branch_id FROM transfers WHERE book_id=$1 AND status=$2)
+		ORDER BY br.name`, bookID, TransferInTransit)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	stock := []BranchStock{}
+	for rows.Next() {
+		var dt BranchStock
+		if err := rows.Scan(&dt.BranchID, &dt.BranchName, &dt.Available, &dt.InTransit); err != nil {
+			return nil, err
+		}
+		stock = append(stock, dt)
+	}
+
+	return stock, rows.Err()
+}
+
+// Returns the branch with the given id, or the oldest branch when id is
+// nil. ErrBranchNotFound is returned if there is no such branch.
+func resolveBranch(q queryer, id *uuid.UUID) (uuid.UUID, error) {
+	var branchID uuid.UUID
+	err := q.QueryRow("SELECT id FROM branches WHERE ($1::uuid IS NULL OR id=$1) AND deleted_at IS NULL ORDER BY created_at LIMIT 1", id).Scan(&branchID)
+	if err == sql.ErrNoRows {
+		return branchID, ErrBranchNotFound
+	}
+	return branchID, err
+}
+
+// CRUD operations
+
+func (dt *Branch) validate() error {
+	if dt.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// Create new branch and insert to database.
+func (dt *Branch) CreateBranch(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	return db.QueryRow("INSERT INTO branches(name, address, created_at, updated_at) VALUES($1, $2, $3, $4) RETURNING id, created_at, updated_at",
+		dt.Name, dt.Address, timestamp, timestamp).Scan(&dt.ID, &dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Updates a specific branch details by id.
+func (dt *Branch) UpdateBranch(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	return db.QueryRow("UPDATE branches SET name=$1, address=$2, updated_at=$3 WHERE id=$4 AND deleted_at IS NULL RETURNING created_at, updated_at",
+		dt.Name, dt.Address, time.Now(), dt.ID).Scan(&dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Soft deletes a specific branch by id. Returns ErrBranchNotEmpty while
+// copies are on its shelves, on their way to or from it, lent from it and
+// not returned, including lost ones that may turn up, or held there.
+func (dt *Branch) DeleteBranch(db *sql.DB) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		var busy bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM books WHERE branch_id=$1 AND number_of_book > 0)
+			OR EXISTS (SELECT 1 FROM transfers WHERE (from_branch_id=$1 OR to_branch_id=$1) AND status IN ($2, $3))
+			OR EXISTS (SELECT 1 FROM issue WHERE branch_id=$1 AND deleted_at IS NULL AND (closed_at IS NULL OR lost_at IS NOT NULL AND found_at IS NULL))
+			OR EXISTS (SELECT 1 FROM reservations WHERE branch_id=$1 AND status=$4)`,
+			dt.ID, TransferRequested, TransferInTransit, ReservationReady).Scan(&busy); err != nil {
+			return err
+		}
+		if busy {
+			return ErrBranchNotEmpty
+		}
+		return softDelete(tx, "branches", dt.ID)
+	})
+}
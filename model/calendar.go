@@ -0,0 +1,303 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidHours   = errors.New("opening hours need a weekday from 0 (Sunday) to 6 (Saturday), each once, and opens before closes in HH:MM")
+	ErrInvalidHoliday = errors.New("date must be a date in YYYY-MM-DD format")
+	ErrHolidayExists  = errors.New("holiday is already in the calendar")
+	ErrHolidayName    = errors.New("name is required")
+)
+
+// Hours a branch, or the whole library, is open on a day of the week.
+type OpeningHours struct {
+	Weekday int    `json:"weekday"`
+	Opens   string `json:"opens"`
+	Closes  string `json:"closes"`
+}
+
+// Day the library is closed. Holidays without a branch close every branch.
+type Holiday struct {
+	ID        uuid.UUID  `json:"id"`
+	BranchID  *uuid.UUID `json:"branchID"`
+	Date      string     `json:"date"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Days a branch is open. A branch without opening hours of its own keeps
+// the hours of the library, and is open every day when the library has
+// none either.
+type calendar struct {
+	hours    map[time.Weekday]OpeningHours
+	holidays map[string]bool
+}
+
+// Calendars loaded once per branch. Loans without a branch use the
+// calendar of the library.
+type calendars map[uuid.UUID]calendar
+
+// Query operations
+
+// Gets opening hours of a branch, or of the library when branchID is nil,
+// from Sunday to Saturday. Days without hours are closed.
+func GetOpeningHours(db *sql.DB, branchID *uuid.UUID) ([]OpeningHours, error) {
+	if branchID != nil {
+		if _, err := resolveBranch(db, branchID); err != nil {
+			return nil, err
+		}
+	}
+	return openingHours(db, branchID)
+}
+
+// Gets holidays in the period from and to, both in YYYY-MM-DD and
+// optional, in date order. Holidays of the library are included when
+// getting the holidays of a branch.
+func GetHolidays(db *sql.DB, branchID *uuid.UUID, from, to string) ([]Holiday, error) {
+	start, end, err := periodBounds(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT id, branch_id, day, name, created_at FROM holidays
+		WHERE ($1::uuid IS NULL OR branch_id IS NULL OR branch_id=$1) AND day >= $2::date AND day < $3::date
+		ORDER BY day, name`, branchID, start.Format(DateLayout), end.Format(DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := []Holiday{}
+	for rows.Next() {
+		var dt Holiday
+		var day time.Time
+		if err := rows.Scan(&dt.ID, &dt.BranchID, &day, &dt.Name, &dt.CreatedAt); err != nil {
+			return nil, err
+		}
+		dt.Date = day.Format(DateLayout)
+		holidays = append(holidays, dt)
+	}
+	return holidays, rows.Err()
+}
+
+// Returns opening hours set for a branch, or for the library when branchID
+// is nil.
+func openingHours(q queryer, branchID *uuid.UUID) ([]OpeningHours, error) {
+	rows, err := q.Query(`SELECT weekday, opens, closes FROM opening_hours
+		WHERE ($1::uuid IS NULL AND branch_id IS NULL) OR branch_id=$1 ORDER BY weekday`, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := []OpeningHours{}
+	for rows.Next() {
+		var dt OpeningHours
+		if err := rows.Scan(&dt.Weekday, &dt.Opens, &dt.Closes); err != nil {
+			return nil, err
+		}
+		hours = append(hours, dt)
+	}
+	return hours, rows.Err()
+}
+
+// Loads the calendar of a branch, or of the library when branchID is nil.
+func loadCalendar(q queryer, branchID *uuid.UUID) (calendar, error) {
+	cal := calendar{holidays: map[string]bool{}}
+	hours, err := openingHours(q, branchID)
+	if err != nil {
+		return cal, err
+	}
+	if len(hours) == 0 && branchID != nil {
+		if hours, err = openingHours(q, nil); err != nil {
+			return cal, err
+		}
+	}
+	if len(hours) > 0 {
+		cal.hours = map[time.Weekday]OpeningHours{}
+		for _, h := range hours {
+			cal.hours[time.Weekday(h.Weekday)] = h
+		}
+	}
+
+	rows, err := q.Query("SELECT day FROM holidays WHERE branch_id IS NULL OR branch_id=$1", branchID)
+	if err != nil {
+		return cal, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return cal, err
+		}
+		cal.holidays[day.Format(DateLayout)] = true
+	}
+	return cal, rows.Err()
+}
+
+// Returns the calendar of a branch, loading it the first time.
+func (c calendars) of(q queryer, branchID *uuid.UUID) (calendar, error) {
+	key := uuid.Nil
+	if branchID != nil {
+		key = *branchID
+	}
+	if cal, ok := c[key]; ok {
+		return cal, nil
+	}
+	cal, err := loadCalendar(q, branchID)
+	if err != nil {
+		return cal, err
+	}
+	c[key] = cal
+	return cal, nil
+}
+
+// Reports whether the branch is open on the day.
+func (c calendar) open(day time.Time) bool {
+	if c.holidays[day.Format(DateLayout)] {
+		return false
+	}
+	if c.hours == nil {
+		return true
+	}
+	_, ok := c.hours[day.Weekday()]
+	return ok
+}
+
+// Returns the day, or the first open day after it. A calendar closed for
+// a whole year leaves the day as it is.
+func (c calendar) nextOpenDay(day time.Time) time.Time {
+	for i := 0; i < 366; i++ {
+		if next := day.AddDate(0, 0, i); c.open(next) {
+			return next
+		}
+	}
+	return day
+}
+
+// Returns the number of closed days among the given number of days
+// following after.
+func (c calendar) closedDays(after time.Time, days int) int {
+	closed := 0
+	for i := 1; i <= days; i++ {
+		if !c.open(after.AddDate(0, 0, i)) {
+			closed++
+		}
+	}
+	return closed
+}
+
+// Returns when the branch closes on the day, at the time of its opening
+// hours or at fallback when it has none.
+func (c calendar) closingTime(day time.Time, fallback string) (time.Time, error) {
+	closes := fallback
+	if h, ok := c.hours[day.Weekday()]; ok {
+		closes = h.Closes
+	}
+	at, err := time.Parse("15:04", closes)
+	if err != nil {
+		return at, errors.Wrapf(err, "invalid closing time %q", closes)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, day.Location()), nil
+}
+
+// Moves the return date of a loan to the first day its branch is open.
+// Reading-room loans are due the day they are issued and aren't moved.
+func (dt *Issue) rollReturnDate(q queryer) error {
+	if dt.LoanType == LoanReadingRoom {
+		return nil
+	}
+	due, err := time.ParseInLocation(DateLayout, dt.ReturnDate, time.Local)
+	if err != nil {
+		return ErrInvalidReturn
+	}
+	cal, err := loadCalendar(q, dt.BranchID)
+	if err != nil {
+		return err
+	}
+	dt.ReturnDate = cal.nextOpenDay(due).Format(DateLayout)
+	return nil
+}
+
+// CRUD operations
+
+func validateHours(hours []OpeningHours) error {
+	seen := map[int]bool{}
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 || seen[h.Weekday] {
+			return ErrInvalidHours
+		}
+		seen[h.Weekday] = true
+		opens, err := time.Parse("15:04", h.Opens)
+		if err != nil {
+			return ErrInvalidHours
+		}
+		closes, err := time.Parse("15:04", h.Closes)
+		if err != nil || !opens.Before(closes) {
+			return ErrInvalidHours
+		}
+	}
+	return nil
+}
+
+// Replaces opening hours of a branch, or of the library when branchID is
+// nil. Days left out are closed; a branch given no hours at all keeps the
+// hours of the library.
+func SetOpeningHours(db *sql.DB, branchID *uuid.UUID, hours []OpeningHours) error {
+	if err := validateHours(hours); err != nil {
+		return err
+	}
+	return withTx(db, func(tx *sql.Tx) error {
+		if branchID != nil {
+			if _, err := resolveBranch(tx, branchID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM opening_hours WHERE ($1::uuid IS NULL AND branch_id IS NULL) OR branch_id=$1", branchID); err != nil {
+			return err
+		}
+		for _, h := range hours {
+			if _, err := tx.Exec("INSERT INTO opening_hours(branch_id, weekday, opens, closes) VALUES($1, $2, $3, $4)",
+				branchID, h.Weekday, h.Opens, h.Closes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Create new holiday and insert to database. ErrHolidayExists is returned
+// when the day is already a holiday of the same branch.
+func (dt *Holiday) CreateHoliday(db *sql.DB) error {
+	if _, err := time.Parse(DateLayout, dt.Date); err != nil {
+		return ErrInvalidHoliday
+	}
+	if dt.Name == "" {
+		return ErrHolidayName
+	}
+	if dt.BranchID != nil {
+		if _, err := resolveBranch(db, dt.BranchID); err != nil {
+			return err
+		}
+	}
+	err := db.QueryRow("INSERT INTO holidays(branch_id, day, name, created_at) VALUES($1, $2::date, $3, $4) ON CONFLICT DO NOTHING RETURNING id, created_at",
+		dt.BranchID, dt.Date, dt.Name, time.Now()).Scan(&dt.ID, &dt.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrHolidayExists
+	}
+	return err
+}
+
+// Deletes a specific holiday by id.
+func (dt *Holiday) DeleteHoliday(db *sql.DB) error {
+	res, err := db.Exec("DELETE FROM holidays WHERE id=$1", dt.ID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
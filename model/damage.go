@@ -0,0 +1,213 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Surcharge per damage type found on return, as a share of the book cost.
+var DamageSurcharges = map[string]float32{
+	"water":         0.3,
+	"torn_pages":    0.2,
+	"missing_pages": 0.5,
+	"writing":       0.1,
+}
+
+// Returned when a damage type has no surcharge defined.
+type UnknownDamageError struct {
+	Type string
+}
+
+func (e *UnknownDamageError) Error() string {
+	known := make([]string, 0, len(DamageSurcharges))
+	for damage := range DamageSurcharges {
+		known = append(known, damage)
+	}
+	sort.Strings(known)
+	return fmt.Sprintf("unknown damage type %q, expected one of: %s", e.Type, strings.Join(known, ", "))
+}
+
+// Damage found on a returned book.
+type Damage struct {
+	Type      string  `json:"type" validate:"required" sql:"damage_type"`
+	Note      string  `json:"note" sql:"note"`
+	Surcharge float32 `json:"surcharge" sql:"surcharge"`
+}
+
+// Assessment of a book at one of its returns.
+type DamageRecord struct {
+	AcceptanceID  uuid.UUID  `json:"acceptanceID"`
+	IssueID       *uuid.UUID `json:"issueID"`
+	UserID        uuid.UUID  `json:"userID"`
+	BookCondition string     `json:"bookCondition"`
+	Damages       []Damage   `json:"damages"`
+	Photos        []string   `json:"photos"`
+	Surcharge     float32    `json:"surcharge"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// Checks the condition grade, damages and photos of a return. Photos may
+// keep file names of the current photos of an acceptance being updated.
+func (dt *Acceptance) assess(q queryer, current ...string) error {
+	if dt.BookCondition == "" {
+		return errors.New("bookCondition is required")
+	}
+	if _, ok := ConditionSurcharges[dt.BookCondition]; !ok {
+		return &UnknownConditionError{Condition: dt.BookCondition}
+	}
+	seen := map[string]bool{}
+	for _, damage := range dt.Damages {
+		if _, ok := DamageSurcharges[damage.Type]; !ok {
+			return &UnknownDamageError{Type: damage.Type}
+		}
+		if seen[damage.Type] {
+			return fmt.Errorf("damage type %q is listed twice", damage.Type)
+		}
+		seen[damage.Type] = true
+	}
+	// The first photo doubles as the acceptance photo.
+	if len(dt.Photos) == 0 && dt.Photo != "" {
+		dt.Photos = []string{dt.Photo}
+	}
+	if len(dt.Photos) == 0 {
+		return errors.New("photo is required")
+	}
+	for i := range dt.Photos {
+		if err := validateImage(q, &dt.Photos[i], current...); err != nil {
+			return err
+		}
+	}
+	dt.Photo = dt.Photos[0]
+	return nil
+}
+
+// Sets the surcharge of each damage and the total on a book of cost. The
+// grade and each damage add their share of the cost; the total never
+// exceeds the cost of the book.
+func (dt *Acceptance) damageSurcharge(cost float32) {
+	total := float64(cost) * float64(ConditionSurcharges[dt.BookCondition])
+	for i := range dt.Damages {
+		dt.Damages[i].Surcharge = roundCents(float64(cost) * float64(DamageSurcharges[dt.Damages[i].Type]))
+		total += float64(dt.Damages[i].Surcharge)
+	}
+	if total > float64(cost) {
+		total = float64(cost)
+	}
+	dt.Surcharge = roundCents(total)
+}
+
+// Stores damages and photos of an acceptance.
+func (dt *Acceptance) saveAssessment(q queryer) error {
+	for _, damage := range dt.Damages {
+		if _, err := q.Exec("INSERT INTO acceptance_damages(acceptance_id, book_id, damage_type, note, surcharge, created_at) VALUES($1, $2, $3, $4, $5, $6)",
+			dt.ID, dt.BookID, damage.Type, damage.Note, damage.Surcharge, dt.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return dt.savePhotos(q)
+}
+
+// Replaces the stored photos of an acceptance with its photos.
+func (dt *Acceptance) savePhotos(q queryer) error {
+	if _, err := q.Exec("DELETE FROM acceptance_photos WHERE acceptance_id=$1", dt.ID); err != nil {
+		return err
+	}
+	for i, photo := range dt.Photos {
+		if _, err := q.Exec("INSERT INTO acceptance_photos(acceptance_id, position, photo) VALUES($1, $2, $3)", dt.ID, i, photo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Loads damages and photos of an acceptance.
+func (dt *Acceptance) loadAssessment(q queryer) error {
+	var err error
+	if dt.Damages, err = acceptanceDamages(q, dt.ID); err != nil {
+		return err
+	}
+	dt.Photos, err = acceptancePhotos(q, dt.ID)
+	return err
+}
+
+func acceptanceDamages(q queryer, acceptanceID uuid.UUID) ([]Damage, error) {
+	rows, err := q.Query("SELECT damage_type, note, surcharge FROM acceptance_damages WHERE acceptance_id=$1 ORDER BY damage_type", acceptanceID)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	damages := []Damage{}
+	for rows.Next() {
+		var d Damage
+		if err := rows.Scan(&d.Type, &d.Note, &d.Surcharge); err != nil {
+			return nil, err
+		}
+		damages = append(damages, d)
+	}
+	return damages, rows.Err()
+}
+
+func acceptancePhotos(q queryer, acceptanceID uuid.UUID) ([]string, error) {
+	rows, err := q.Query("SELECT photo FROM acceptance_photos WHERE acceptance_id=$1 ORDER BY position", acceptanceID)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	photos := []string{}
+	for rows.Next() {
+		var photo string
+		if err := rows.Scan(&photo); err != nil {
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	return photos, rows.Err()
+}
+
+// Gets condition assessments of a book from all its returns, newest first.
+func GetDamageHistory(db *sql.DB, bookID uuid.UUID) ([]DamageRecord, error) {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM book WHERE id=$1 AND deleted_at IS NULL)", bookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrBookNotFound
+	}
+	rows, err := db.Query("SELECT id, issue_id, user_id, book_condition, surcharge, created_at FROM acceptance WHERE book_id=$1 AND deleted_at IS NULL ORDER BY created_at DESC", bookID)
+	if err != nil {
+		return nil, err
+	}
+	history := []DamageRecord{}
+	for rows.Next() {
+		var r DamageRecord
+		if err := rows.Scan(&r.AcceptanceID, &r.IssueID, &r.UserID, &r.BookCondition, &r.Surcharge, &r.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		history = append(history, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range history {
+		if history[i].Damages, err = acceptanceDamages(db, history[i].AcceptanceID); err != nil {
+			return nil, err
+		}
+		if history[i].Photos, err = acceptancePhotos(db, history[i].AcceptanceID); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,299 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of discount rules.
+const (
+	// Applies when the reader returns at least MinBooks books on the same day.
+	DiscountMultiBook = "multi_book"
+	// Applies when the reader has closed at least MinLoans loans before.
+	DiscountLoyalty = "loyalty"
+	// Applies to books of CategoryID.
+	DiscountCategory = "category"
+	// Applies to returns between StartsOn and EndsOn.
+	DiscountCampaign = "campaign"
+)
+
+// Highest total discount in percent that rules may give on a return.
+var MaxDiscountPercent float32 = 50
+
+// Defines discount rule model. Rules apply to the rent of a return.
+// Stackable rules add up; a non-stackable rule can't be combined, so the
+// larger of the best non-stackable rule and the sum of stackable rules
+// wins. The total is capped at MaxDiscountPercent.
+type DiscountRule struct {
+	ID         uuid.UUID  `json:"id"       sql:"uuid"`
+	Name       string     `json:"name" validate:"required" sql:"name"`
+	Kind       string     `json:"kind" validate:"required" sql:"kind"`
+	Percent    float32    `json:"percent" validate:"required" sql:"percent"`
+	MinBooks   int        `json:"minBooks" sql:"min_books"`
+	MinLoans   int        `json:"minLoans" sql:"min_loans"`
+	CategoryID *uuid.UUID `json:"categoryID" sql:"category_id"`
+	StartsOn   string     `json:"startsOn" sql:"starts_on"`
+	EndsOn     string     `json:"endsOn" sql:"ends_on"`
+	Stackable  bool       `json:"stackable" sql:"stackable"`
+	Active     *bool      `json:"active" sql:"active"`
+	CreatedAt  time.Time  `json:"createdAt" sql:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" sql:"updated_at"`
+}
+
+// Explains one discount given on an acceptance.
+type AppliedDiscount struct {
+	RuleID  *uuid.UUID `json:"ruleID,omitempty"`
+	Name    string     `json:"name"`
+	Kind    string     `json:"kind"`
+	Percent float32    `json:"percent"`
+	Amount  float32    `json:"amount"`
+	Reason  string     `json:"reason"`
+}
+
+// Kind of the explanation entry for the reader's personal discount.
+const DiscountReader = "reader"
+
+// Discounts applied on an acceptance, stored as jsonb.
+type DiscountExplanation []AppliedDiscount
+
+func (e DiscountExplanation) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(e)
+}
+
+func (e *DiscountExplanation) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	case nil:
+		*e = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into DiscountExplanation", src)
+}
+
+// Facts about a return that discount rules are matched against.
+type discountContext struct {
+	returned    time.Time
+	booksToday  int
+	closedLoans int
+	categories  map[uuid.UUID]bool
+}
+
+// Query operations
+
+const discountColumns = "id, name, kind, percent, min_books, min_loans, category_id, COALESCE(to_char(starts_on, 'YYYY-MM-DD'), ''), COALESCE(to_char(ends_on, 'YYYY-MM-DD'), ''), stackable, active, created_at, updated_at"
+
+func (dt *DiscountRule) scan(row interface{ Scan(...interface{}) error }) error {
+	return row.Scan(&dt.ID, &dt.Name, &dt.Kind, &dt.Percent, &dt.MinBooks, &dt.MinLoans, &dt.CategoryID, &dt.StartsOn, &dt.EndsOn, &dt.Stackable, &dt.Active, &dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Gets a specific discount rule by id.
+func (dt *DiscountRule) GetDiscountRule(db *sql.DB) error {
+	return dt.scan(db.QueryRow("SELECT "+discountColumns+" FROM discount_rules WHERE id=$1", dt.ID))
+}
+
+// Gets discount rules. Only active ones if activeOnly is set.
+func GetDiscountRules(db queryer, activeOnly bool) ([]DiscountRule, error) {
+	rows, err := db.Query("SELECT "+discountColumns+" FROM discount_rules WHERE active OR NOT $1 ORDER BY created_at", activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	rules := []DiscountRule{}
+
+	// Store query results into rules variable if no errors.
+	for rows.Next() {
+		var dt DiscountRule
+		if err := dt.scan(rows); err != nil {
+			return nil, err
+		}
+		rules = append(rules, dt)
+	}
+
+	return rules, rows.Err()
+}
+
+// Reports whether the rule applies to a return and why.
+func (dt *DiscountRule) matches(c discountContext) (bool, string) {
+	day := c.returned.Format(DateLayout)
+	if dt.StartsOn != "" && day < dt.StartsOn || dt.EndsOn != "" && day > dt.EndsOn {
+		return false, ""
+	}
+	switch dt.Kind {
+	case DiscountMultiBook:
+		return c.booksToday >= dt.MinBooks, fmt.Sprintf("%d books returned on %s, at least %d required", c.booksToday, day, dt.MinBooks)
+	case DiscountLoyalty:
+		return c.closedLoans >= dt.MinLoans, fmt.Sprintf("%d earlier loans, at least %d required", c.closedLoans, dt.MinLoans)
+	case DiscountCategory:
+		return dt.CategoryID != nil && c.categories[*dt.CategoryID], "book belongs to the promoted category"
+	case DiscountCampaign:
+		return true, fmt.Sprintf("campaign runs from %s to %s", dt.StartsOn, dt.EndsOn)
+	}
+	return false, ""
+}
+
+// Applies active discount rules to the rent of a return. Returns the
+// discounts given, with amounts rounded to cents.
+func applyDiscountRules(q queryer, rent float32, userID, bookID uuid.UUID, returned time.Time) ([]AppliedDiscount, error) {
+	rules, err := GetDiscountRules(q, true)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	c := discountContext{returned: returned, categories: map[uuid.UUID]bool{}}
+	start := time.Date(returned.Year(), returned.Month(), returned.Day(), 0, 0, 0, 0, time.Local)
+	// The book being returned counts towards books returned today.
+	if err := q.QueryRow("SELECT COUNT(*) + 1 FROM acceptance WHERE user_id=$1 AND created_at >= $2 AND deleted_at IS NULL",
+		userID, start).Scan(&c.booksToday); err != nil {
+		return nil, err
+	}
+	if err := q.QueryRow("SELECT COUNT(*) FROM issue WHERE user_id=$1 AND closed_at IS NOT NULL AND deleted_at IS NULL",
+		userID).Scan(&c.closedLoans); err != nil {
+		return nil, err
+	}
+	rows, err := q.Query("SELECT categories_id FROM book_categories WHERE book_id=$1", bookID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.categories[id] = true
+	}
+	rows.Close()
+
+	var stacked []AppliedDiscount
+	var best *AppliedDiscount
+	var stackedPercent float32
+	for i := range rules {
+		rule := &rules[i]
+		ok, reason := rule.matches(c)
+		if !ok {
+			continue
+		}
+		applied := AppliedDiscount{RuleID: &rule.ID, Name: rule.Name, Kind: rule.Kind, Percent: rule.Percent, Reason: reason}
+		if rule.Stackable {
+			stacked = append(stacked, applied)
+			stackedPercent += rule.Percent
+		} else if best == nil || rule.Percent > best.Percent {
+			best = &applied
+		}
+	}
+	applied := stacked
+	if best != nil && best.Percent >= stackedPercent {
+		applied = []AppliedDiscount{*best}
+	}
+
+	// Cut the last discounts down so the total stays within the cap.
+	remaining := MaxDiscountPercent
+	result := []AppliedDiscount{}
+	for _, a := range applied {
+		if remaining <= 0 {
+			break
+		}
+		if a.Percent > remaining {
+			a.Percent = remaining
+			a.Reason += fmt.Sprintf("; capped at %v%% total", MaxDiscountPercent)
+		}
+		remaining -= a.Percent
+		a.Amount = roundCents(float64(rent) * float64(a.Percent) / 100)
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// CRUD operations
+
+func (dt *DiscountRule) validate() error {
+	if dt.Name == "" {
+		return errors.New("name is required")
+	}
+	if dt.Percent <= 0 || dt.Percent > 100 {
+		return errors.New("percent must be between 0 and 100")
+	}
+	for _, date := range []string{dt.StartsOn, dt.EndsOn} {
+		if _, err := time.Parse(DateLayout, date); date != "" && err != nil {
+			return errors.New("startsOn and endsOn must be dates in YYYY-MM-DD format")
+		}
+	}
+	if dt.StartsOn != "" && dt.EndsOn != "" && dt.EndsOn < dt.StartsOn {
+		return errors.New("endsOn cannot be before startsOn")
+	}
+	switch dt.Kind {
+	case DiscountMultiBook:
+		if dt.MinBooks < 2 {
+			return errors.New("minBooks must be at least 2")
+		}
+	case DiscountLoyalty:
+		if dt.MinLoans < 1 {
+			return errors.New("minLoans must be positive")
+		}
+	case DiscountCategory:
+		if dt.CategoryID == nil {
+			return errors.New("categoryID is required")
+		}
+	case DiscountCampaign:
+		if dt.StartsOn == "" || dt.EndsOn == "" {
+			return errors.New("startsOn and endsOn are required")
+		}
+	default:
+		return fmt.Errorf("kind must be one of: %s, %s, %s, %s", DiscountMultiBook, DiscountLoyalty, DiscountCategory, DiscountCampaign)
+	}
+	return nil
+}
+
+// Returns date for a nullable date column.
+func nullDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+	return date
+}
+
+// Create new discount rule and insert to database. A rule created without
+// Active is active.
+func (dt *DiscountRule) CreateDiscountRule(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	return dt.scan(db.QueryRow(
+		"INSERT INTO discount_rules(name, kind, percent, min_books, min_loans, category_id, starts_on, ends_on, stackable, active, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, true), $11, $12) RETURNING "+discountColumns,
+		dt.Name, dt.Kind, dt.Percent, dt.MinBooks, dt.MinLoans, dt.CategoryID, nullDate(dt.StartsOn), nullDate(dt.EndsOn), dt.Stackable, dt.Active, timestamp, timestamp))
+}
+
+// Updates a specific discount rule by id. A rule updated without Active
+// keeps being active or inactive.
+func (dt *DiscountRule) UpdateDiscountRule(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	return dt.scan(db.QueryRow(
+		"UPDATE discount_rules SET name=$1, kind=$2, percent=$3, min_books=$4, min_loans=$5, category_id=$6, starts_on=$7, ends_on=$8, stackable=$9, active=COALESCE($10, active), updated_at=$11 WHERE id=$12 RETURNING "+discountColumns,
+		dt.Name, dt.Kind, dt.Percent, dt.MinBooks, dt.MinLoans, dt.CategoryID, nullDate(dt.StartsOn), nullDate(dt.EndsOn), dt.Stackable, dt.Active, timestamp, dt.ID))
+}
+
+// Deletes a specific discount rule by id.
+func (dt *DiscountRule) DeleteDiscountRule(db *sql.DB) error {
+	res, err := db.Exec("DELETE FROM discount_rules WHERE id=$1", dt.ID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
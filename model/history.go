@@ -0,0 +1,129 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Loan a reader has out, with the title of the book and whether it is
+// overdue.
+type Loan struct {
+	IssueID         uuid.UUID `json:"issueID"`
+	BookID          uuid.UUID `json:"bookID"`
+	Title           string    `json:"title"`
+	IssuedAt        time.Time `json:"issuedAt"`
+	ReturnDate      string    `json:"returnDate"`
+	Renewals        int       `json:"renewals"`
+	PreliminaryCost float32   `json:"preliminaryCost"`
+	Overdue         bool      `json:"overdue"`
+	DaysOverdue     int       `json:"daysOverdue"`
+}
+
+// Past loan of a reader with its return, if the book came back.
+type HistoryEntry struct {
+	IssueID       uuid.UUID  `json:"issueID"`
+	BookID        uuid.UUID  `json:"bookID"`
+	Title         string     `json:"title"`
+	IssuedAt      time.Time  `json:"issuedAt"`
+	ReturnDate    string     `json:"returnDate"`
+	ClosedAt      *time.Time `json:"closedAt"`
+	LostAt        *time.Time `json:"lostAt"`
+	AcceptanceID  *uuid.UUID `json:"acceptanceID"`
+	BookCondition *string    `json:"bookCondition"`
+	LateDays      *int       `json:"lateDays"`
+	FinalCost     *float32   `json:"finalCost"`
+}
+
+// Query operations
+
+// Gets open loans of a reader issued within from and to, oldest due date
+// first. Empty from or to leaves the period open.
+func GetUserLoans(db *sql.DB, userID uuid.UUID, from, to string, limit, page int) ([]Loan, error) {
+	start, end, err := periodBounds(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUser(db, userID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT i.id, i.book_id, b.name, i.created_at, to_char(i.return_date, 'YYYY-MM-DD'), i.renewals, i.preliminary_cost
+		FROM issue i JOIN book b ON b.id = i.book_id
+		WHERE i.user_id=$1 AND i.closed_at IS NULL AND i.deleted_at IS NULL AND i.created_at >= $2 AND i.created_at < $3
+		ORDER BY i.return_date, i.created_at LIMIT $4 OFFSET $5`,
+		userID, start, end, limit, limit*(page-1))
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	now := time.Now()
+	loans := []Loan{}
+
+	// Store query results into loans variable if no errors.
+	for rows.Next() {
+		var dt Loan
+		if err := rows.Scan(&dt.IssueID, &dt.BookID, &dt.Title, &dt.IssuedAt, &dt.ReturnDate, &dt.Renewals, &dt.PreliminaryCost); err != nil {
+			return nil, err
+		}
+		if left := loanDays(now, dt.ReturnDate); left < 0 {
+			dt.Overdue, dt.DaysOverdue = true, -left
+		}
+		loans = append(loans, dt)
+	}
+
+	return loans, rows.Err()
+}
+
+// Gets closed loans of a reader issued within from and to, newest first.
+// Returned loans carry their acceptance; lost ones have none. Empty from or
+// to leaves the period open.
+func GetUserHistory(db *sql.DB, userID uuid.UUID, from, to string, limit, page int) ([]HistoryEntry, error) {
+	start, end, err := periodBounds(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUser(db, userID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT i.id, i.book_id, b.name, i.created_at, to_char(i.return_date, 'YYYY-MM-DD'), i.closed_at, i.lost_at,
+			a.id, a.book_condition, a.late_days, a.final_cost
+		FROM issue i JOIN book b ON b.id = i.book_id
+		LEFT JOIN acceptance a ON a.issue_id = i.id AND a.deleted_at IS NULL
+		WHERE i.user_id=$1 AND i.closed_at IS NOT NULL AND i.deleted_at IS NULL AND i.created_at >= $2 AND i.created_at < $3
+		ORDER BY i.created_at DESC, i.id LIMIT $4 OFFSET $5`,
+		userID, start, end, limit, limit*(page-1))
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	history := []HistoryEntry{}
+
+	// Store query results into history variable if no errors.
+	for rows.Next() {
+		var dt HistoryEntry
+		if err := rows.Scan(&dt.IssueID, &dt.BookID, &dt.Title, &dt.IssuedAt, &dt.ReturnDate, &dt.ClosedAt, &dt.LostAt,
+			&dt.AcceptanceID, &dt.BookCondition, &dt.LateDays, &dt.FinalCost); err != nil {
+			return nil, err
+		}
+		history = append(history, dt)
+	}
+
+	return history, rows.Err()
+}
+
+// Returns ErrUserNotFound unless the reader exists.
+func checkUser(q queryer, userID uuid.UUID) error {
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id=$1 AND deleted_at IS NULL)", userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defines uploaded image model. Uploads with identical content share one
+// stored file, identified by the SHA-256 of the content.
+type Image struct {
+	ID           uuid.UUID `json:"id"       sql:"uuid"`
+	Filename     string    `json:"filename" sql:"filename"`
+	OriginalName string    `json:"originalName" sql:"original_name"`
+	ContentType  string    `json:"contentType" sql:"content_type"`
+	Size         int64     `json:"size" sql:"size"`
+	Width        int       `json:"width" sql:"width"`
+	Height       int       `json:"height" sql:"height"`
+	SHA256       string    `json:"sha256,omitempty" sql:"sha256"`
+	CreatedAt    time.Time `json:"createdAt" sql:"created_at"`
+}
+
+// Defines stored image content shared by uploads with the same hash.
+type ImageBlob struct {
+	SHA256    string    `json:"sha256" sql:"sha256"`
+	Filename  string    `json:"filename" sql:"filename"`
+	RefCount  int       `json:"refCount" sql:"ref_count"`
+	CreatedAt time.Time `json:"createdAt" sql:"created_at"`
+}
+
+var ErrImageNotFound = errors.New("photo must be the ID of an uploaded image")
+
+// Query operations
+
+// Gets a specific image by id.
+func (dt *Image) GetImage(db *sql.DB) error {
+	return db.QueryRow("SELECT filename, original_name, content_type, size, width, height, COALESCE(sha256, ''), created_at FROM images WHERE id=$1",
+		dt.ID).Scan(&dt.Filename, &dt.OriginalName, &dt.ContentType, &dt.Size, &dt.Width, &dt.Height, &dt.SHA256, &dt.CreatedAt)
+}
+
+// Gets images created before the given time that no book, author or acceptance refers to.
+func GetUnreferencedImages(db *sql.DB, before time.Time) ([]Image, error) {
+	rows, err := db.Query(`SELECT id, filename, original_name, content_type, size, width, height, COALESCE(sha256, ''), created_at FROM images
+		WHERE created_at < $1
+		AND NOT EXISTS (SELECT 1 FROM book WHERE book.photo = images.id::text)
+		AND NOT EXISTS (SELECT 1 FROM authors WHERE authors.photo = images.id::text)
+		AND NOT EXISTS (SELECT 1 FROM acceptance WHERE acceptance.photo = images.id::text)
+		AND NOT EXISTS (SELECT 1 FROM acceptance_photos WHERE acceptance_photos.photo = images.id::text)`, before)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	images := []Image{}
+
+	// Store query results into images variable if no errors.
+	for rows.Next() {
+		var dt Image
+		if err := rows.Scan(&dt.ID, &dt.Filename, &dt.OriginalName, &dt.ContentType, &dt.Size, &dt.Width, &dt.Height, &dt.SHA256, &dt.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, dt)
+	}
+
+	return images, rows.Err()
+}
+
+// Gets all stored image contents.
+func GetImageBlobs(db *sql.DB) ([]ImageBlob, error) {
+	rows, err := db.Query("SELECT sha256, filename, ref_count, created_at FROM image_blobs ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	blobs := []ImageBlob{}
+
+	// Store query results into blobs variable if no errors.
+	for rows.Next() {
+		var dt ImageBlob
+		if err := rows.Scan(&dt.SHA256, &dt.Filename, &dt.RefCount, &dt.CreatedAt); err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, dt)
+	}
+
+	return blobs, rows.Err()
+}
+
+// Gets images uploaded before deduplication. They have no content hash.
+func GetUnhashedImages(db *sql.DB) ([]Image, error) {
+	rows, err := db.Query("SELECT id, filename, original_name, content_type, size, width, height, created_at FROM images WHERE sha256 IS NULL ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	images := []Image{}
+
+	// Store query results into images variable if no errors.
+	for rows.Next() {
+		var dt Image
+		if err := rows.Scan(&dt.ID, &dt.Filename, &dt.OriginalName, &dt.ContentType, &dt.Size, &dt.Width, &dt.Height, &dt.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, dt)
+	}
+
+	return images, rows.Err()
+}
+
+// CRUD operations
+
+// Create new image record and insert to database. If no earlier upload has
+// the same content, store is called to save the files before the record is
+// committed; otherwise the existing content gets one more reference.
+func (dt *Image) CreateImage(db *sql.DB, store func() error) error {
+	if dt.Filename == "" {
+		return errors.New("filename is required")
+	}
+	if dt.SHA256 == "" {
+		return errors.New("sha256 is required")
+	}
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		// Lock the content so a concurrent cleanup can't remove its files meanwhile.
+		var refCount int
+		err := tx.QueryRow("SELECT ref_count FROM image_blobs WHERE sha256=$1 FOR UPDATE", dt.SHA256).Scan(&refCount)
+		if err == sql.ErrNoRows {
+			if err := store(); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO image_blobs(sha256, filename, ref_count, created_at) VALUES($1, $2, 1, $3) ON CONFLICT (sha256) DO UPDATE SET ref_count = image_blobs.ref_count + 1",
+			dt.SHA256, dt.Filename, timestamp)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow(
+			"INSERT INTO images(filename, original_name, content_type, size, width, height, sha256, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at", dt.Filename, dt.OriginalName, dt.ContentType, dt.Size, dt.Width, dt.Height, dt.SHA256, timestamp).Scan(&dt.ID, &dt.CreatedAt)
+	})
+}
+
+// Deletes a specific image record by id. When no other image shares the
+// content, release is called to remove the files once the change is
+// committed, so a failed delete never leaves records without files.
+func (dt *Image) DeleteImage(db *sql.DB, release func() error) error {
+	var unused bool
+	err := withTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM images WHERE id=$1", dt.ID); err != nil {
+			return err
+		}
+		if dt.SHA256 == "" {
+			// Images uploaded before deduplication own their files.
+			unused = true
+			return nil
+		}
+		var refCount int
+		err := tx.QueryRow("UPDATE image_blobs SET ref_count = ref_count - 1 WHERE sha256=$1 RETURNING ref_count", dt.SHA256).Scan(&refCount)
+		if err == sql.ErrNoRows {
+			unused = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if refCount > 0 {
+			return nil
+		}
+		if _, err := tx.Exec("DELETE FROM image_blobs WHERE sha256=$1", dt.SHA256); err != nil {
+			return err
+		}
+		unused = true
+		return nil
+	})
+	if err != nil || !unused {
+		return err
+	}
+	return release()
+}
+
+// Checks that photo holds the ID of an uploaded image and rewrites it in
+// the canonical form cleanup compares against. A file name stored before
+// uploads had IDs is kept as it is when it is one of the current photos of
+// the record being updated.
+func validateImage(q queryer, photo *string, current ...string) error {
+	id, err := uuid.Parse(*photo)
+	if err != nil {
+		for _, legacy := range current {
+			if *photo == legacy {
+				return nil
+			}
+		}
+		return ErrImageNotFound
+	}
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM images WHERE id=$1)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrImageNotFound
+	}
+	*photo = id.String()
+	return nil
+}
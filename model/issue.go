@@ -3,6 +3,7 @@ package model
 import (
 	"database/sql"
 	"github.com/pkg/errors"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,22 +18,37 @@ type Issue struct {
 	BookID            uuid.UUID `json:"bookID" validate:"required" sql:"book_id"`
 	ReturnDate        string    `json:"returnDate" validate:"required" sql:"return_date"`
 	PreliminaryCost   float32   `json:"preliminaryCost" validate:"required" sql:"preliminary_cost"`
+	PricePerDay       float32   `json:"pricePerDay" sql:"price_per_day"`
+	Discount          float32   `json:"discount" sql:"discount"`
+	Renewals          int       `json:"renewals" sql:"renewals"`
+	BranchID          *uuid.UUID `json:"branchID" sql:"branch_id"`
+	LoanType          string    `json:"loanType" sql:"loan_type"`
+	ClosedAt          *time.Time `json:"closedAt" sql:"closed_at"`
+	LostAt            *time.Time `json:"lostAt" sql:"lost_at"`
+	FoundAt           *time.Time `json:"foundAt" sql:"found_at"`
+	FlaggedAt         *time.Time `json:"flaggedAt" sql:"flagged_at"`
 	CreatedAt         time.Time `json:"createdAt" sql:"created_at"`
 	UpdatedAt         time.Time `json:"updatedAt" sql:"updated_at"`
+	Override          *LendingOverride `json:"override,omitempty"`
 }
 
 // Query operations
 
 // Gets a specific user by id.
 func (dt *Issue) GetIssue(db *sql.DB) error {
-	return db.QueryRow("SELECT user_id, book_id, return_date, preliminary_cost, created_at, updated_at FROM issue WHERE id=$1",
-		dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, &dt.CreatedAt, &dt.UpdatedAt)
+	return dt.reload(db)
+}
+
+// Reads the issue by id, also to read it back after a change.
+func (dt *Issue) reload(q queryer) error {
+	return q.QueryRow("SELECT user_id, book_id, to_char(return_date, 'YYYY-MM-DD'), preliminary_cost, price_per_day, discount, renewals, branch_id, loan_type, closed_at, lost_at, found_at, flagged_at, created_at, updated_at FROM issue WHERE id=$1 AND deleted_at IS NULL",
+		dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, &dt.PricePerDay, &dt.Discount, &dt.Renewals, &dt.BranchID, &dt.LoanType, &dt.ClosedAt, &dt.LostAt, &dt.FoundAt, &dt.FlaggedAt, &dt.CreatedAt, &dt.UpdatedAt)
 }
 
 // Gets users. Limit count and start position in db.
 func GetIssues(db *sql.DB, field, sort string, limit, page int) ([]Issue, error) {
 
-	rows, err := db.Query(  "SELECT * FROM issue ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query(  "SELECT id, user_id, book_id, to_char(return_date, 'YYYY-MM-DD'), preliminary_cost, price_per_day, discount, renewals, branch_id, loan_type, closed_at, lost_at, found_at, flagged_at, created_at, updated_at FROM issue WHERE deleted_at IS NULL ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
@@ -46,7 +62,7 @@ func GetIssues(db *sql.DB, field, sort string, limit, page int) ([]Issue, error)
 	// Store query results into user variable if no errors.
 	for rows.Next() {
 		var dt Issue
-		if err := rows.Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+		if err := rows.Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, &dt.PricePerDay, &dt.Discount, &dt.Renewals, &dt.BranchID, &dt.LoanType, &dt.ClosedAt, &dt.LostAt, &dt.FoundAt, &dt.FlaggedAt, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
 			return nil, err
 		}
 		issue = append(issue, dt)
@@ -57,49 +73,216 @@ func GetIssues(db *sql.DB, field, sort string, limit, page int) ([]Issue, error)
 
 // CRUD operations
 
-// Create new user and insert to database.
+// Create new issue and insert to database. The loan is checked against
+// lending blocks and policy, priced from a snapshot and lent from the
+// reader's held copy or from stock, all in one transaction.
 func (dt *Issue) CreateIssue(db *sql.DB) error {
-	if dt.ReturnDate == "" {
-		return errors.New("date is required")
+	if dt.PreliminaryCost != 0 || dt.PricePerDay != 0 || dt.Discount != 0 {
+		return ErrClientCost
 	}
-	if dt.PreliminaryCost == 0 {
-		return errors.New("cost cannot be zero")
-	}
-
-	// Scan db after creation if user exists using new user id.
 	timestamp := time.Now()
-	err := db.QueryRow(
-		"INSERT INTO issue(user_id, book_id, return_date, preliminary_cost, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING id, user_id, book_id, return_date, preliminary_cost, created_at, updated_at", &dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, timestamp, timestamp).Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, &dt.CreatedAt, &dt.UpdatedAt)
-	if err != nil {
-		return err
-	}
-	return nil
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := checkLendable(tx, dt.UserID, dt.BookID); err != nil {
+			return err
+		}
+		if err := dt.checkLoanType(tx, timestamp); err != nil {
+			return err
+		}
+		if dt.BranchID != nil {
+			if _, err := resolveBranch(tx, dt.BranchID); err != nil {
+				return err
+			}
+		}
+		block, err := dt.checkLendingBlock(tx, timestamp)
+		if err != nil {
+			return err
+		}
+		policy, err := loanPolicy(tx, dt.UserID, dt.BookID)
+		if err != nil {
+			return err
+		}
+		if err := policy.checkLoan(tx, dt, timestamp); err != nil {
+			return err
+		}
+		if err := dt.snapshotPrice(tx, policy); err != nil {
+			return err
+		}
+		held, heldAt, err := pickUpHold(tx, dt.UserID, dt.BookID, timestamp)
+		if err != nil {
+			return err
+		}
+		if held && heldAt != nil {
+			// The held copy is lent from the branch holding it.
+			if dt.BranchID != nil && *dt.BranchID != *heldAt {
+				return ErrHoldAtOtherBranch
+			}
+			dt.BranchID = heldAt
+		}
+		if !held {
+			from, err := takeCopy(tx, dt.BookID, dt.BranchID)
+			if err != nil {
+				return err
+			}
+			dt.BranchID = &from
+		}
+		if err := dt.rollReturnDate(tx); err != nil {
+			return err
+		}
+		dt.PremCostFunc(loanDays(timestamp, dt.ReturnDate))
+		// Scan db after creation if issue exists using new issue id.
+		if err := tx.QueryRow(
+			"INSERT INTO issue(user_id, book_id, return_date, preliminary_cost, price_per_day, discount, branch_id, loan_type, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, user_id, book_id, to_char(return_date, 'YYYY-MM-DD'), preliminary_cost, price_per_day, discount, renewals, branch_id, loan_type, closed_at, created_at, updated_at", dt.UserID, dt.BookID, dt.ReturnDate, dt.PreliminaryCost, dt.PricePerDay, dt.Discount, dt.BranchID, dt.LoanType, timestamp, timestamp).Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, &dt.PricePerDay, &dt.Discount, &dt.Renewals, &dt.BranchID, &dt.LoanType, &dt.ClosedAt, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return err
+		}
+		if dt.Override != nil {
+			return dt.Override.record(tx, dt, block, timestamp)
+		}
+		return nil
+	})
 }
 
-// Updates a specific user details by id.
+// Updates a specific issue details by id. The preliminary cost is
+// recalculated from the price snapshot, which is only taken again when the
+// loan moves to another reader or book. A loan moved to a blocked reader
+// needs an Override like a new loan. Moving an open loan to another
+// book passes the old copy on and takes a new one. The loan type is kept.
 func (dt *Issue) UpdateIssue(db *sql.DB) error {
 	if dt.ReturnDate == "" {
 		return errors.New("date is required")
 	}
-	if dt.PreliminaryCost == 0 {
-		return errors.New("cost cannot be zero")
+	if dt.PreliminaryCost != 0 || dt.PricePerDay != 0 || dt.Discount != 0 {
+		return ErrClientCost
+	}
+	due, err := time.ParseInLocation(DateLayout, dt.ReturnDate, time.Local)
+	if err != nil {
+		return ErrInvalidReturn
 	}
 	timestamp := time.Now()
-	_, err :=
-		db.Exec("UPDATE issue SET user_id=$1, book_id=$2, return_date=$3, preliminary_cost=$4, updated_at=$5 WHERE id=$6 RETURNING id, user_id, book_id, return_date, preliminary_cost, created_at, updated_at", &dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PreliminaryCost, timestamp, dt.ID)
-
-	return err
+	return withTx(db, func(tx *sql.Tx) error {
+		var previous Issue
+		if err := tx.QueryRow("SELECT user_id, book_id, price_per_day, discount, branch_id, loan_type, closed_at, created_at FROM issue WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&previous.UserID, &previous.BookID, &previous.PricePerDay, &previous.Discount, &previous.BranchID, &previous.LoanType, &previous.ClosedAt, &previous.CreatedAt); err != nil {
+			return err
+		}
+		if due.Format(DateLayout) < previous.CreatedAt.Format(DateLayout) {
+			return ErrReturnBefore
+		}
+		dt.PricePerDay, dt.Discount, dt.BranchID, dt.LoanType = previous.PricePerDay, previous.Discount, previous.BranchID, previous.LoanType
+		var block *LendingBlockError
+		if previous.UserID != dt.UserID {
+			var err error
+			if block, err = dt.checkLendingBlock(tx, timestamp); err != nil {
+				return err
+			}
+		} else {
+			dt.Override = nil
+		}
+		if previous.UserID != dt.UserID || previous.BookID != dt.BookID {
+			if err := checkLendable(tx, dt.UserID, dt.BookID); err != nil {
+				return err
+			}
+			policy, err := loanPolicy(tx, dt.UserID, dt.BookID)
+			if err != nil {
+				return err
+			}
+			if err := dt.snapshotPrice(tx, policy); err != nil {
+				return err
+			}
+		}
+		if err := dt.checkLoanType(tx, previous.CreatedAt); err != nil {
+			return err
+		}
+		dt.PremCostFunc(loanDays(previous.CreatedAt, dt.ReturnDate))
+		if previous.ClosedAt == nil && previous.BookID != dt.BookID {
+			if err := releaseLoanCopy(tx, previous.BookID, previous.BranchID, timestamp); err != nil {
+				return err
+			}
+			from, err := takeCopy(tx, dt.BookID, previous.BranchID)
+			if err != nil {
+				return err
+			}
+			dt.BranchID = &from
+		}
+		if err := tx.QueryRow("UPDATE issue SET user_id=$1, book_id=$2, return_date=$3, preliminary_cost=$4, price_per_day=$5, discount=$6, branch_id=$7, updated_at=$8 WHERE id=$9 RETURNING renewals, closed_at, created_at, updated_at",
+			dt.UserID, dt.BookID, dt.ReturnDate, dt.PreliminaryCost, dt.PricePerDay, dt.Discount, dt.BranchID, timestamp, dt.ID).Scan(&dt.Renewals, &dt.ClosedAt, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return err
+		}
+		if dt.Override != nil {
+			return dt.Override.record(tx, dt, block, timestamp)
+		}
+		return nil
+	})
 }
 
-// Deletes a specific user by id.
+// Soft deletes a specific issue by id. An open loan passes its copy on.
 func (dt *Issue) DeleteIssue(db *sql.DB) error {
-	_, err := db.Exec("DELETE FROM issue WHERE id=$1", dt.ID)
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT book_id, branch_id, closed_at FROM issue WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&dt.BookID, &dt.BranchID, &dt.ClosedAt); err != nil {
+			return err
+		}
+		if err := softDelete(tx, "issue", dt.ID); err != nil {
+			return err
+		}
+		if dt.ClosedAt != nil {
+			return nil
+		}
+		return releaseLoanCopy(tx, dt.BookID, dt.BranchID, timestamp)
+	})
+}
 
-	return err
+// Restores a soft deleted issue by id. An open loan takes its copy from
+// stock again, so ErrNoCopiesAvailable is returned if it has been lent out.
+func (dt *Issue) RestoreIssue(db *sql.DB) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT user_id, book_id, branch_id, closed_at FROM issue WHERE id=$1 AND deleted_at IS NOT NULL FOR UPDATE",
+			dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.BranchID, &dt.ClosedAt); err != nil {
+			return err
+		}
+		if err := restore(tx, "issue", dt.ID); err != nil {
+			return err
+		}
+		if dt.ClosedAt != nil {
+			return nil
+		}
+		if err := checkLendable(tx, dt.UserID, dt.BookID); err != nil {
+			return err
+		}
+		_, err := takeCopy(tx, dt.BookID, dt.BranchID)
+		return err
+	})
 }
 
-func (dt *Issue) PremCostFunc(b *Book) {
-	dt.PreliminaryCost = b.PricePerDay * 30
+// Takes snapshot of the daily price of the book under lending policy and
+// of the reader's discount.
+func (dt *Issue) snapshotPrice(q queryer, p LendingPolicy) error {
+	var pricePerDay float32
+	if err := q.QueryRow("SELECT price_per_day FROM book WHERE id=$1", dt.BookID).Scan(&pricePerDay); err != nil {
+		return err
+	}
+	if err := q.QueryRow("SELECT discount FROM users WHERE id=$1", dt.UserID).Scan(&dt.Discount); err != nil {
+		return err
+	}
+	dt.PricePerDay = p.LoanCost(pricePerDay, 1)
+	return nil
+}
+
+// Sets preliminary cost of the loan for the given number of days from the
+// price snapshot, less the reader's discount in percent. Rounded to cents.
+func (dt *Issue) PremCostFunc(days int) {
+	cost := float64(dt.PricePerDay) * float64(days) * (1 - float64(dt.Discount)/100)
+	dt.PreliminaryCost = float32(math.Round(cost*100) / 100)
+}
+
+// Returns number of days from the day of from to a return date.
+func loanDays(from time.Time, returnDate string) int {
+	due, err := time.ParseInLocation(DateLayout, returnDate, time.Local)
+	if err != nil {
+		return 0
+	}
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	return int(math.Round(due.Sub(start).Hours() / 24))
 }
 
 
@@ -0,0 +1,239 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of ledger entries. Charges increase what a reader owes, credits
+// decrease it.
+const (
+	ChargeLoanFee  = "loan_fee"
+	ChargeLateFee  = "late_fee"
+	ChargeDamage   = "damage"
+	ChargeLostBook = "lost_book"
+	CreditPayment  = "payment"
+	CreditWaiver   = "waiver"
+	// Money paid back to a reader; reverses part of a payment.
+	ChargeRefund = "refund"
+	// Reverses the lost book charges when the book turns up.
+	CreditLostReversal = "lost_book_reversal"
+	// Balances carried over from the former free-text indebtedness field.
+	LedgerMigrated = "migrated"
+)
+
+var ErrInvalidPeriod = errors.New("from and to must be dates in YYYY-MM-DD format")
+
+// Reports whether entries of kind reduce the balance.
+func isCredit(kind string) bool {
+	return kind == CreditPayment || kind == CreditWaiver || kind == CreditLostReversal
+}
+
+// Defines ledger entry model. Amount is positive for charges and negative
+// for credits; a reader's balance is the sum of their entries.
+type LedgerEntry struct {
+	ID           uuid.UUID  `json:"id"       sql:"uuid"`
+	UserID       uuid.UUID  `json:"userID" sql:"user_id"`
+	Kind         string     `json:"kind" validate:"required" sql:"kind"`
+	Amount       float32    `json:"amount" validate:"required" sql:"amount"`
+	Description  string     `json:"description" sql:"description"`
+	IssueID      *uuid.UUID `json:"issueID" sql:"issue_id"`
+	AcceptanceID *uuid.UUID `json:"acceptanceID" sql:"acceptance_id"`
+	PaymentID    *uuid.UUID `json:"paymentID" sql:"payment_id"`
+	Balance      float32    `json:"balance"`
+	CreatedAt    time.Time  `json:"createdAt" sql:"created_at"`
+}
+
+// Reader statement: entries in a period with running balances.
+type Statement struct {
+	UserID         uuid.UUID     `json:"userID"`
+	OpeningBalance float32       `json:"openingBalance"`
+	Entries        []LedgerEntry `json:"entries"`
+	Balance        float32       `json:"balance"`
+}
+
+// Reader owing money to the library.
+type Debtor struct {
+	UserID    uuid.UUID `json:"userID"`
+	Firstname string    `json:"firstName"`
+	Surname   string    `json:"surname"`
+	Email     string    `json:"email"`
+	Balance   float32   `json:"balance"`
+}
+
+// Query operations
+
+// Gets the statement of a reader. Empty from or to leaves the period open.
+func GetStatement(db *sql.DB, userID uuid.UUID, from, to string) (Statement, error) {
+	st := Statement{UserID: userID, Entries: []LedgerEntry{}}
+	start, end, err := periodBounds(from, to)
+	if err != nil {
+		return st, err
+	}
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id=$1)", userID).Scan(&exists); err != nil {
+		return st, err
+	}
+	if !exists {
+		return st, ErrUserNotFound
+	}
+	if err := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE user_id=$1 AND created_at < $2",
+		userID, start).Scan(&st.OpeningBalance); err != nil {
+		return st, err
+	}
+	rows, err := db.Query("SELECT id, user_id, kind, amount, description, issue_id, acceptance_id, payment_id, created_at FROM ledger_entries WHERE user_id=$1 AND created_at >= $2 AND created_at < $3 ORDER BY created_at, id",
+		userID, start, end)
+	if err != nil {
+		return st, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	balance := float64(st.OpeningBalance)
+	// Store query results into statement entries if no errors.
+	for rows.Next() {
+		var dt LedgerEntry
+		if err := rows.Scan(&dt.ID, &dt.UserID, &dt.Kind, &dt.Amount, &dt.Description, &dt.IssueID, &dt.AcceptanceID, &dt.PaymentID, &dt.CreatedAt); err != nil {
+			return st, err
+		}
+		balance += float64(dt.Amount)
+		dt.Balance = roundCents(balance)
+		st.Entries = append(st.Entries, dt)
+	}
+	st.Balance = roundCents(balance)
+	return st, rows.Err()
+}
+
+// Returns the time range of a period given as optional YYYY-MM-DD dates.
+// The end date is inclusive.
+func periodBounds(from, to string) (time.Time, time.Time, error) {
+	start := time.Time{}
+	end := time.Date(9999, 1, 1, 0, 0, 0, 0, time.Local)
+	var err error
+	if from != "" {
+		if start, err = time.ParseInLocation(DateLayout, from, time.Local); err != nil {
+			return start, end, ErrInvalidPeriod
+		}
+	}
+	if to != "" {
+		if end, err = time.ParseInLocation(DateLayout, to, time.Local); err != nil {
+			return start, end, ErrInvalidPeriod
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// Returns the balance of a reader.
+func balanceOf(q queryer, userID uuid.UUID) (float32, error) {
+	var balance float32
+	err := q.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE user_id=$1", userID).Scan(&balance)
+	return roundCents(float64(balance)), err
+}
+
+// Gets readers whose balance is above min, largest debt first.
+func GetDebtors(db *sql.DB, min float32) ([]Debtor, error) {
+	rows, err := db.Query(`SELECT u.id, u.firstname, u.surname, u.email, ROUND(SUM(l.amount)::numeric, 2)::float
+		FROM ledger_entries l JOIN users u ON u.id = l.user_id
+		WHERE u.deleted_at IS NULL
+		GROUP BY u.id HAVING ROUND(SUM(l.amount)::numeric, 2) > $1
+		ORDER BY 5 DESC, u.surname`, min)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	debtors := []Debtor{}
+
+	// Store query results into debtors variable if no errors.
+	for rows.Next() {
+		var dt Debtor
+		if err := rows.Scan(&dt.UserID, &dt.Firstname, &dt.Surname, &dt.Email, &dt.Balance); err != nil {
+			return nil, err
+		}
+		debtors = append(debtors, dt)
+	}
+
+	return debtors, rows.Err()
+}
+
+// CRUD operations
+
+// Adds an entry to a reader's ledger. Amount is given as a positive value
+// and stored negative for credits. Zero amounts are skipped.
+func postEntry(q queryer, dt *LedgerEntry, at time.Time) error {
+	if dt.Amount == 0 {
+		return nil
+	}
+	if isCredit(dt.Kind) {
+		dt.Amount = -dt.Amount
+	}
+	return q.QueryRow(
+		"INSERT INTO ledger_entries(user_id, kind, amount, description, issue_id, acceptance_id, payment_id, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at",
+		dt.UserID, dt.Kind, dt.Amount, dt.Description, dt.IssueID, dt.AcceptanceID, dt.PaymentID, at).Scan(&dt.ID, &dt.CreatedAt)
+}
+
+// Posts the charges of a return: the rent less discounts, late fees and
+// the surcharge for the book condition.
+func postReturnCharges(q queryer, dt *Acceptance, rent float32) error {
+	charges := []LedgerEntry{
+		{Kind: ChargeLoanFee, Amount: roundCents(float64(rent - dt.Discount)), Description: fmt.Sprintf("loan fee for %d days", dt.DaysKept)},
+		{Kind: ChargeLateFee, Amount: dt.LateFee, Description: fmt.Sprintf("late fee for %d days", dt.LateDays)},
+		{Kind: ChargeDamage, Amount: dt.Surcharge, Description: damageDescription(dt)},
+	}
+	for i := range charges {
+		charges[i].UserID, charges[i].IssueID, charges[i].AcceptanceID = dt.UserID, dt.IssueID, &dt.ID
+		if err := postEntry(q, &charges[i], dt.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Describes the condition of a returned book for its damage charge.
+func damageDescription(dt *Acceptance) string {
+	description := "book returned " + dt.BookCondition
+	for _, damage := range dt.Damages {
+		description += ", " + strings.ReplaceAll(damage.Type, "_", " ")
+	}
+	return description
+}
+
+// Records a manual charge or waiver on a reader's ledger. Payments and
+// refunds are posted by the payments they belong to.
+func (dt *LedgerEntry) CreateLedgerEntry(db *sql.DB) error {
+	switch dt.Kind {
+	case ChargeLoanFee, ChargeLateFee, ChargeDamage, ChargeLostBook, CreditWaiver:
+	default:
+		return fmt.Errorf("kind must be one of: %s, %s, %s, %s, %s",
+			ChargeLoanFee, ChargeLateFee, ChargeDamage, ChargeLostBook, CreditWaiver)
+	}
+	if dt.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if dt.Description == "" {
+		return errors.New("description is required")
+	}
+	dt.Amount = roundCents(float64(dt.Amount))
+	return withTx(db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id=$1 AND deleted_at IS NULL)", dt.UserID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+		if err := postEntry(tx, dt, time.Now()); err != nil {
+			return err
+		}
+		var err error
+		dt.Balance, err = balanceOf(tx, dt.UserID)
+		return err
+	})
+}
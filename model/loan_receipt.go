@@ -0,0 +1,139 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/pdf"
+)
+
+// Receipt given to a reader for a book they borrowed.
+type LoanReceipt struct {
+	IssueID         uuid.UUID `json:"issueID"`
+	Reader          string    `json:"reader"`
+	Email           string    `json:"email"`
+	Title           string    `json:"title"`
+	Branch          string    `json:"branch"`
+	IssuedAt        time.Time `json:"issuedAt"`
+	ReturnDate      string    `json:"returnDate"`
+	PricePerDay     float32   `json:"pricePerDay"`
+	Discount        float32   `json:"discount"`
+	PreliminaryCost float32   `json:"preliminaryCost"`
+}
+
+// Receipt given to a reader for a book they returned.
+type ReturnReceipt struct {
+	AcceptanceID        uuid.UUID           `json:"acceptanceID"`
+	IssueID             *uuid.UUID          `json:"issueID"`
+	Reader              string              `json:"reader"`
+	Email               string              `json:"email"`
+	Title               string              `json:"title"`
+	Branch              string              `json:"branch"`
+	ReturnedAt          time.Time           `json:"returnedAt"`
+	BookCondition       string              `json:"bookCondition"`
+	Damages             []Damage            `json:"damages"`
+	DaysKept            int                 `json:"daysKept"`
+	LateDays            int                 `json:"lateDays"`
+	LateFee             float32             `json:"lateFee"`
+	Surcharge           float32             `json:"surcharge"`
+	Discount            float32             `json:"discount"`
+	DiscountExplanation DiscountExplanation `json:"discountExplanation"`
+	FinalCost           float32             `json:"finalCost"`
+}
+
+// Query operations
+
+// Gets the receipt of a loan by issue id.
+func GetLoanReceipt(db *sql.DB, issueID uuid.UUID) (LoanReceipt, error) {
+	r := LoanReceipt{IssueID: issueID}
+	err := db.QueryRow(`SELECT u.firstname || ' ' || u.surname, u.email, b.name, COALESCE(br.name, ''),
+			i.created_at, to_char(i.return_date, 'YYYY-MM-DD'), i.price_per_day, i.discount, i.preliminary_cost
+		FROM issue i JOIN users u ON u.id = i.user_id JOIN book b ON b.id = i.book_id
+		LEFT JOIN branches br ON br.id = i.branch_id
+		WHERE i.id=$1 AND i.deleted_at IS NULL`, issueID).Scan(&r.Reader, &r.Email, &r.Title, &r.Branch,
+		&r.IssuedAt, &r.ReturnDate, &r.PricePerDay, &r.Discount, &r.PreliminaryCost)
+	return r, err
+}
+
+// Gets the receipt of a return by acceptance id.
+func GetReturnReceipt(db *sql.DB, acceptanceID uuid.UUID) (ReturnReceipt, error) {
+	r := ReturnReceipt{AcceptanceID: acceptanceID}
+	err := db.QueryRow(`SELECT a.issue_id, u.firstname || ' ' || u.surname, u.email, b.name, COALESCE(br.name, ''),
+			a.created_at, a.book_condition, a.days_kept, a.late_days, a.late_fee, a.surcharge, a.discount, a.discount_explanation, a.final_cost
+		FROM acceptance a JOIN users u ON u.id = a.user_id JOIN book b ON b.id = a.book_id
+		LEFT JOIN branches br ON br.id = a.branch_id
+		WHERE a.id=$1 AND a.deleted_at IS NULL`, acceptanceID).Scan(&r.IssueID, &r.Reader, &r.Email, &r.Title, &r.Branch,
+		&r.ReturnedAt, &r.BookCondition, &r.DaysKept, &r.LateDays, &r.LateFee, &r.Surcharge, &r.Discount, &r.DiscountExplanation, &r.FinalCost)
+	if err != nil {
+		return r, err
+	}
+	r.Damages, err = acceptanceDamages(db, acceptanceID)
+	return r, err
+}
+
+// Renders the receipt as a PDF. Names and titles outside Latin-1 show "?"
+// for the characters the PDF fonts lack.
+func (r LoanReceipt) PDF() []byte {
+	doc := pdf.New()
+	doc.Line(18, true, "Loan receipt")
+	doc.Line(10, false, "No. "+r.IssueID.String())
+	doc.Space(12)
+	receiptParty(doc, r.Reader, r.Email, r.Branch)
+	doc.Space(12)
+	doc.Line(12, true, r.Title)
+	doc.Line(11, false, "Issued: "+r.IssuedAt.Format(DateLayout))
+	doc.Line(11, false, "Due: "+r.ReturnDate)
+	doc.Line(11, false, fmt.Sprintf("Price per day: %.2f", r.PricePerDay))
+	if r.Discount > 0 {
+		doc.Line(11, false, fmt.Sprintf("Reader discount: %g%%", r.Discount))
+	}
+	doc.Space(12)
+	doc.Line(12, true, fmt.Sprintf("Preliminary cost: %.2f", r.PreliminaryCost))
+	doc.Line(9, false, "The final cost is calculated when the book is returned.")
+	return doc.Bytes()
+}
+
+// Renders the receipt as a PDF. Names and titles outside Latin-1 show "?"
+// for the characters the PDF fonts lack.
+func (r ReturnReceipt) PDF() []byte {
+	doc := pdf.New()
+	doc.Line(18, true, "Return receipt")
+	doc.Line(10, false, "No. "+r.AcceptanceID.String())
+	doc.Space(12)
+	receiptParty(doc, r.Reader, r.Email, r.Branch)
+	doc.Space(12)
+	doc.Line(12, true, r.Title)
+	doc.Line(11, false, "Returned: "+r.ReturnedAt.Format(DateLayout))
+	doc.Line(11, false, fmt.Sprintf("Days kept: %d", r.DaysKept))
+	doc.Line(11, false, "Condition: "+r.BookCondition)
+	for _, damage := range r.Damages {
+		line := fmt.Sprintf("  Damage: %s, %.2f", strings.ReplaceAll(damage.Type, "_", " "), damage.Surcharge)
+		if damage.Note != "" {
+			line += " (" + damage.Note + ")"
+		}
+		doc.Line(11, false, line)
+	}
+	if r.LateDays > 0 {
+		doc.Line(11, false, fmt.Sprintf("Late fee for %d days: %.2f", r.LateDays, r.LateFee))
+	}
+	if r.Surcharge > 0 {
+		doc.Line(11, false, fmt.Sprintf("Condition surcharge: %.2f", r.Surcharge))
+	}
+	for _, applied := range r.DiscountExplanation {
+		doc.Line(11, false, fmt.Sprintf("Discount, %s: -%.2f", applied.Name, applied.Amount))
+	}
+	doc.Space(12)
+	doc.Line(12, true, fmt.Sprintf("Final cost: %.2f", r.FinalCost))
+	return doc.Bytes()
+}
+
+// Writes who a receipt is for and where it was issued.
+func receiptParty(doc *pdf.Document, reader, email, branch string) {
+	doc.Line(11, false, "Reader: "+reader+" <"+email+">")
+	if branch != "" {
+		doc.Line(11, false, "Branch: "+branch)
+	}
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Fee charged on top of the book cost when a book is lost.
+var LostProcessingFee float32 = 5
+
+var (
+	ErrNotLost      = errors.New("loan is not declared lost")
+	ErrAlreadyFound = errors.New("lost book has already been found")
+)
+
+// Declares the book of an open loan lost. The loan is closed, the reader
+// is charged the book cost plus LostProcessingFee and the copy never goes
+// back to stock.
+func (dt *Issue) DeclareLost(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT user_id, book_id, closed_at FROM issue WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.ClosedAt); err != nil {
+			return err
+		}
+		if dt.ClosedAt != nil {
+			return ErrLoanClosed
+		}
+		var title string
+		var cost float32
+		if err := tx.QueryRow("SELECT name, cost FROM book WHERE id=$1", dt.BookID).Scan(&title, &cost); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE issue SET closed_at=$1, lost_at=$1, updated_at=$1 WHERE id=$2", timestamp, dt.ID); err != nil {
+			return err
+		}
+		charges := []LedgerEntry{
+			{Kind: ChargeLostBook, Amount: cost, Description: fmt.Sprintf("lost book %q", title)},
+			{Kind: ChargeLostBook, Amount: LostProcessingFee, Description: "processing fee for lost book"},
+		}
+		for i := range charges {
+			charges[i].UserID, charges[i].IssueID = dt.UserID, &dt.ID
+			if err := postEntry(tx, &charges[i], timestamp); err != nil {
+				return err
+			}
+		}
+		return dt.reload(tx)
+	})
+}
+
+// Reverses a lost declaration when the book turns up. The lost book
+// charges are credited back to the reader and the copy goes to the next
+// reservation or back to stock of the branch it was lent from.
+func (dt *Issue) ReverseLost(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT user_id, book_id, branch_id, lost_at, found_at FROM issue WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.BranchID, &dt.LostAt, &dt.FoundAt); err != nil {
+			return err
+		}
+		if dt.LostAt == nil {
+			return ErrNotLost
+		}
+		if dt.FoundAt != nil {
+			return ErrAlreadyFound
+		}
+		var charged float32
+		if err := tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE issue_id=$1 AND kind=$2",
+			dt.ID, ChargeLostBook).Scan(&charged); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE issue SET found_at=$1, updated_at=$1 WHERE id=$2", timestamp, dt.ID); err != nil {
+			return err
+		}
+		credit := LedgerEntry{UserID: dt.UserID, IssueID: &dt.ID, Kind: CreditLostReversal,
+			Amount: roundCents(float64(charged)), Description: "lost book found"}
+		if err := postEntry(tx, &credit, timestamp); err != nil {
+			return err
+		}
+		if err := releaseLoanCopy(tx, dt.BookID, dt.BranchID, timestamp); err != nil {
+			return err
+		}
+		return dt.reload(tx)
+	})
+}
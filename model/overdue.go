@@ -0,0 +1,119 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fields overdue loans can be sorted by.
+var overdueSorts = map[string]func(a, b OverdueLoan) bool{
+	"daysOverdue": func(a, b OverdueLoan) bool { return a.DaysOverdue < b.DaysOverdue },
+	"fine":        func(a, b OverdueLoan) bool { return a.Fine < b.Fine },
+	"returnDate":  func(a, b OverdueLoan) bool { return a.ReturnDate < b.ReturnDate },
+	"reader":      func(a, b OverdueLoan) bool { return a.Surname+" "+a.Firstname < b.Surname+" "+b.Firstname },
+	"title":       func(a, b OverdueLoan) bool { return a.Title < b.Title },
+}
+
+// Returned when overdue loans are sorted by an unknown field or order.
+type InvalidSortError struct {
+	Field, Order string
+}
+
+func (e *InvalidSortError) Error() string {
+	fields := make([]string, 0, len(overdueSorts))
+	for field := range overdueSorts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fmt.Sprintf("cannot sort by %q %q, expected one of: %s and asc or desc", e.Field, e.Order, strings.Join(fields, ", "))
+}
+
+// Open loan past its return date with the fine accrued so far.
+type OverdueLoan struct {
+	IssueID     uuid.UUID `json:"issueID"`
+	UserID      uuid.UUID `json:"userID"`
+	Firstname   string    `json:"firstname"`
+	Surname     string    `json:"surname"`
+	Email       string    `json:"email"`
+	BookID      uuid.UUID `json:"bookID"`
+	Title       string    `json:"title"`
+	ReturnDate  string    `json:"returnDate"`
+	DaysOverdue int       `json:"daysOverdue"`
+	LateDays    int       `json:"lateDays"`
+	Fine        float32   `json:"fine"`
+}
+
+// Query operations
+
+// Gets every overdue loan sorted by field in order, most days overdue
+// first by default. The fine is what the reader would be charged on
+// returning the book today, so it only accrues after the grace days of the
+// lending policy and not on days the branch of the loan is closed.
+func GetOverdueLoans(db *sql.DB, field, order string) ([]OverdueLoan, error) {
+	if field == "" {
+		field = "daysOverdue"
+	}
+	if order == "" {
+		order = "desc"
+	}
+	less, ok := overdueSorts[field]
+	order = strings.ToLower(order)
+	if !ok || order != "asc" && order != "desc" {
+		return nil, &InvalidSortError{Field: field, Order: order}
+	}
+
+	now := time.Now()
+	rows, err := db.Query(`SELECT i.id, u.id, u.firstname, u.surname, u.email, u.reader_type, i.book_id, b.name, to_char(i.return_date, 'YYYY-MM-DD'), i.branch_id
+		FROM issue i JOIN users u ON u.id = i.user_id JOIN book b ON b.id = i.book_id
+		WHERE i.closed_at IS NULL AND i.deleted_at IS NULL AND i.return_date < $1::date`, now.Format(DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	loans := []OverdueLoan{}
+	readerTypes := []string{}
+	branches := []*uuid.UUID{}
+	for rows.Next() {
+		var dt OverdueLoan
+		var readerType string
+		var branchID *uuid.UUID
+		if err := rows.Scan(&dt.IssueID, &dt.UserID, &dt.Firstname, &dt.Surname, &dt.Email, &readerType, &dt.BookID, &dt.Title, &dt.ReturnDate, &branchID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		loans = append(loans, dt)
+		readerTypes = append(readerTypes, readerType)
+		branches = append(branches, branchID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cache := calendars{}
+	for i := range loans {
+		policy, err := policyFor(db, readerTypes[i], loans[i].BookID)
+		if err != nil {
+			return nil, err
+		}
+		cal, err := cache.of(db, branches[i])
+		if err != nil {
+			return nil, err
+		}
+		loans[i].DaysOverdue = -loanDays(now, loans[i].ReturnDate)
+		if loans[i].LateDays, loans[i].Fine, err = lateFee(cal, loans[i].ReturnDate, policy.GraceDays, now); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(loans, func(i, j int) bool {
+		if order == "desc" {
+			return less(loans[j], loans[i])
+		}
+		return less(loans[i], loans[j])
+	})
+	return loans, nil
+}
@@ -0,0 +1,330 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payment methods.
+const (
+	PaymentCash = "cash"
+	PaymentCard = "card"
+)
+
+// Payment kinds.
+const (
+	KindPayment = "payment"
+	KindRefund  = "refund"
+)
+
+// Payment statuses. Card payments may wait for settlement; only settled
+// payments get a receipt, reduce the reader's balance and count as profit.
+const (
+	PaymentPending = "pending"
+	PaymentSettled = "settled"
+	PaymentVoided  = "voided"
+)
+
+var (
+	ErrAcceptanceNotFound = errors.New("acceptance not found")
+	ErrOverpayment        = errors.New("amount exceeds what is owed")
+	ErrOverRefund         = errors.New("amount exceeds what is left to refund")
+	ErrNotRefundable      = errors.New("only settled payments can be refunded")
+	ErrPaymentNotPending  = errors.New("payment is not pending")
+	ErrNoReceipt          = errors.New("payment has no receipt until it is settled")
+)
+
+// Defines payment model. Refunds are payments of kind refund referring to
+// the payment they pay back; Amount is always positive.
+type Payment struct {
+	ID            uuid.UUID  `json:"id"       sql:"uuid"`
+	ReceiptNumber *int64     `json:"receiptNumber" sql:"receipt_number"`
+	UserID        uuid.UUID  `json:"userID" sql:"user_id"`
+	AcceptanceID  *uuid.UUID `json:"acceptanceID" sql:"acceptance_id"`
+	RefundOf      *uuid.UUID `json:"refundOf" sql:"refund_of"`
+	Kind          string     `json:"kind" sql:"kind"`
+	Method        string     `json:"method" validate:"required" sql:"method"`
+	Amount        float32    `json:"amount" validate:"required" sql:"amount"`
+	Status        string     `json:"status" sql:"status"`
+	SettledAt     *time.Time `json:"settledAt" sql:"settled_at"`
+	CreatedAt     time.Time  `json:"createdAt" sql:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" sql:"updated_at"`
+}
+
+// Receipt for a settled payment or refund.
+type Receipt struct {
+	Number   int64     `json:"number"`
+	IssuedAt time.Time `json:"issuedAt"`
+	Reader   string    `json:"reader"`
+	Payment  Payment   `json:"payment"`
+	Balance  float32   `json:"balance"`
+}
+
+// Query operations
+
+const paymentColumns = "id, receipt_number, user_id, acceptance_id, refund_of, kind, method, amount, status, settled_at, created_at, updated_at"
+
+func (dt *Payment) scan(row interface{ Scan(...interface{}) error }) error {
+	return row.Scan(&dt.ID, &dt.ReceiptNumber, &dt.UserID, &dt.AcceptanceID, &dt.RefundOf, &dt.Kind, &dt.Method, &dt.Amount, &dt.Status, &dt.SettledAt, &dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Gets a specific payment by id.
+func (dt *Payment) GetPayment(db *sql.DB) error {
+	return dt.scan(db.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id=$1", dt.ID))
+}
+
+// Gets payments and refunds of a reader, newest first.
+func GetUserPayments(db *sql.DB, userID uuid.UUID) ([]Payment, error) {
+	rows, err := db.Query("SELECT "+paymentColumns+" FROM payments WHERE user_id=$1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	payments := []Payment{}
+
+	// Store query results into payments variable if no errors.
+	for rows.Next() {
+		var dt Payment
+		if err := dt.scan(rows); err != nil {
+			return nil, err
+		}
+		payments = append(payments, dt)
+	}
+
+	return payments, rows.Err()
+}
+
+// Gets the receipt of a settled payment.
+func GetReceipt(db *sql.DB, paymentID uuid.UUID) (Receipt, error) {
+	var r Receipt
+	if err := r.Payment.scan(db.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id=$1", paymentID)); err != nil {
+		return r, err
+	}
+	if r.Payment.ReceiptNumber == nil {
+		return r, ErrNoReceipt
+	}
+	r.Number, r.IssuedAt = *r.Payment.ReceiptNumber, *r.Payment.SettledAt
+	if err := db.QueryRow("SELECT firstname || ' ' || surname FROM users WHERE id=$1", r.Payment.UserID).Scan(&r.Reader); err != nil {
+		return r, err
+	}
+	// Balance right after the receipt was issued.
+	err := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE user_id=$1 AND created_at <= $2",
+		r.Payment.UserID, r.IssuedAt).Scan(&r.Balance)
+	r.Balance = roundCents(float64(r.Balance))
+	return r, err
+}
+
+// Returns money received less refunds over settled payments.
+func GetProfit(db *sql.DB) (float32, error) {
+	var profit float32
+	err := db.QueryRow("SELECT COALESCE(SUM(CASE WHEN kind=$1 THEN -amount ELSE amount END), 0) FROM payments WHERE status=$2",
+		KindRefund, PaymentSettled).Scan(&profit)
+	return roundCents(float64(profit)), err
+}
+
+// Returns what is left to pay: the reader's balance less pending
+// payments. For an acceptance it is at most its final cost less payments
+// not voided and plus refunds, so the balance paid off in general isn't
+// collected again per acceptance.
+func outstanding(q queryer, userID uuid.UUID, acceptanceID *uuid.UUID) (float32, error) {
+	// Settled payments are already part of the balance.
+	balance, err := balanceOf(q, userID)
+	if err != nil {
+		return 0, err
+	}
+	var pending float32
+	if err := q.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM payments WHERE user_id=$1 AND status=$2",
+		userID, PaymentPending).Scan(&pending); err != nil {
+		return 0, err
+	}
+	owed := roundCents(float64(balance - pending))
+	if acceptanceID == nil {
+		return owed, nil
+	}
+
+	var cost, paid float32
+	if err := q.QueryRow("SELECT final_cost FROM acceptance WHERE id=$1", acceptanceID).Scan(&cost); err != nil {
+		return 0, err
+	}
+	if err := q.QueryRow("SELECT COALESCE(SUM(CASE WHEN kind=$1 THEN -amount ELSE amount END), 0) FROM payments WHERE acceptance_id=$2 AND status<>$3",
+		KindRefund, acceptanceID, PaymentVoided).Scan(&paid); err != nil {
+		return 0, err
+	}
+	if remaining := roundCents(float64(cost - paid)); remaining < owed {
+		return remaining, nil
+	}
+	return owed, nil
+}
+
+// CRUD operations
+
+// Takes the next receipt number. The counter row is locked until the
+// transaction ends, so numbers have no gaps.
+func nextReceiptNumber(tx *sql.Tx) (int64, error) {
+	var number int64
+	err := tx.QueryRow("UPDATE receipt_counter SET last_number=last_number+1 RETURNING last_number").Scan(&number)
+	return number, err
+}
+
+// Settles a payment: numbers its receipt and posts it to the ledger.
+func (dt *Payment) settle(tx *sql.Tx, at time.Time) error {
+	number, err := nextReceiptNumber(tx)
+	if err != nil {
+		return err
+	}
+	if err := dt.scan(tx.QueryRow("UPDATE payments SET status=$1, receipt_number=$2, settled_at=$3, updated_at=$3 WHERE id=$4 RETURNING "+paymentColumns,
+		PaymentSettled, number, at, dt.ID)); err != nil {
+		return err
+	}
+	entry := LedgerEntry{UserID: dt.UserID, Kind: CreditPayment, Amount: dt.Amount, AcceptanceID: dt.AcceptanceID, PaymentID: &dt.ID,
+		Description: fmt.Sprintf("%s payment, receipt %d", dt.Method, number)}
+	if dt.Kind == KindRefund {
+		entry.Kind, entry.Description = ChargeRefund, fmt.Sprintf("%s refund, receipt %d", dt.Method, number)
+	}
+	return postEntry(tx, &entry, at)
+}
+
+func (dt *Payment) insert(tx *sql.Tx, at time.Time) error {
+	return dt.scan(tx.QueryRow(
+		"INSERT INTO payments(user_id, acceptance_id, refund_of, kind, method, amount, status, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "+paymentColumns,
+		dt.UserID, dt.AcceptanceID, dt.RefundOf, dt.Kind, dt.Method, dt.Amount, PaymentPending, at, at))
+}
+
+func (dt *Payment) validate() error {
+	if dt.Method != PaymentCash && dt.Method != PaymentCard {
+		return fmt.Errorf("method must be %s or %s", PaymentCash, PaymentCard)
+	}
+	if dt.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	dt.Amount = roundCents(float64(dt.Amount))
+	return nil
+}
+
+// Records a payment against an acceptance or the reader's balance and
+// settles it. A card payment created with status pending waits for
+// SettlePayment. Partial payments are allowed, overpayments are not.
+func (dt *Payment) CreatePayment(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	pending := dt.Status == PaymentPending
+	if pending && dt.Method != PaymentCard {
+		return errors.New("only card payments can be pending")
+	}
+	if !pending && dt.Status != "" && dt.Status != PaymentSettled {
+		return fmt.Errorf("status must be %s or %s", PaymentPending, PaymentSettled)
+	}
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if dt.AcceptanceID != nil {
+			var userID uuid.UUID
+			err := tx.QueryRow("SELECT user_id FROM acceptance WHERE id=$1 AND deleted_at IS NULL", dt.AcceptanceID).Scan(&userID)
+			if err == sql.ErrNoRows {
+				return ErrAcceptanceNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if dt.UserID != uuid.Nil && dt.UserID != userID {
+				return errors.New("acceptance belongs to another reader")
+			}
+			dt.UserID = userID
+		}
+		// Payments of a reader are serialized on the user row.
+		var id uuid.UUID
+		if err := tx.QueryRow("SELECT id FROM users WHERE id=$1 AND deleted_at IS NULL FOR NO KEY UPDATE", dt.UserID).Scan(&id); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		owed, err := outstanding(tx, dt.UserID, dt.AcceptanceID)
+		if err != nil {
+			return err
+		}
+		if dt.Amount > owed {
+			return ErrOverpayment
+		}
+		dt.Kind, dt.RefundOf = KindPayment, nil
+		if err := dt.insert(tx, timestamp); err != nil {
+			return err
+		}
+		if pending {
+			return nil
+		}
+		return dt.settle(tx, timestamp)
+	})
+}
+
+// Settles a pending payment by id.
+func (dt *Payment) SettlePayment(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.scan(tx.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id=$1 FOR UPDATE", dt.ID)); err != nil {
+			return err
+		}
+		if dt.Status != PaymentPending {
+			return ErrPaymentNotPending
+		}
+		return dt.settle(tx, timestamp)
+	})
+}
+
+// Voids a pending payment by id.
+func (dt *Payment) VoidPayment(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.scan(tx.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id=$1 FOR UPDATE", dt.ID)); err != nil {
+			return err
+		}
+		if dt.Status != PaymentPending {
+			return ErrPaymentNotPending
+		}
+		return dt.scan(tx.QueryRow("UPDATE payments SET status=$1, updated_at=$2 WHERE id=$3 RETURNING "+paymentColumns,
+			PaymentVoided, timestamp, dt.ID))
+	})
+}
+
+// Refunds all or part of a settled payment. The refund is settled at once
+// with its own receipt; the method defaults to the method of the payment.
+func (dt *Payment) RefundPayment(db *sql.DB, paymentID uuid.UUID) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		var original Payment
+		if err := original.scan(tx.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id=$1 FOR UPDATE", paymentID)); err != nil {
+			return err
+		}
+		if original.Kind != KindPayment || original.Status != PaymentSettled {
+			return ErrNotRefundable
+		}
+		if dt.Amount == 0 {
+			dt.Amount = original.Amount
+		}
+		if dt.Method == "" {
+			dt.Method = original.Method
+		}
+		if err := dt.validate(); err != nil {
+			return err
+		}
+		var refunded float32
+		if err := tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM payments WHERE refund_of=$1 AND status<>$2",
+			paymentID, PaymentVoided).Scan(&refunded); err != nil {
+			return err
+		}
+		if dt.Amount > roundCents(float64(original.Amount-refunded)) {
+			return ErrOverRefund
+		}
+		dt.UserID, dt.AcceptanceID, dt.RefundOf, dt.Kind = original.UserID, original.AcceptanceID, &original.ID, KindRefund
+		if err := dt.insert(tx, timestamp); err != nil {
+			return err
+		}
+		return dt.settle(tx, timestamp)
+	})
+}
@@ -0,0 +1,248 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reader type of users created without one.
+const DefaultReaderType = "regular"
+
+// Defines lending policy model. A policy applies to books of a category
+// and readers of a type; an empty scope field matches everything.
+type LendingPolicy struct {
+	ID              uuid.UUID  `json:"id"       sql:"uuid"`
+	CategoryID      *uuid.UUID `json:"categoryID" sql:"category_id"`
+	ReaderType      string     `json:"readerType" sql:"reader_type"`
+	LoanPeriod      int        `json:"loanPeriod" validate:"required" sql:"loan_period"`
+	MaxLoans        int        `json:"maxLoans" validate:"required" sql:"max_loans"`
+	MaxRenewals     int        `json:"maxRenewals" sql:"max_renewals"`
+	PriceMultiplier float32    `json:"priceMultiplier" validate:"required" sql:"price_multiplier"`
+	GraceDays       int        `json:"graceDays" sql:"grace_days"`
+	CreatedAt       time.Time  `json:"createdAt" sql:"created_at"`
+	UpdatedAt       time.Time  `json:"updatedAt" sql:"updated_at"`
+}
+
+// Policy used when no stored policy matches a loan.
+var DefaultPolicy = LendingPolicy{
+	LoanPeriod:      30,
+	MaxLoans:        5,
+	MaxRenewals:     2,
+	PriceMultiplier: 1,
+}
+
+var ErrPolicyExists = errors.New("a policy for this category and reader type already exists")
+
+// Explains one reason lending policy refused a loan.
+type PolicyViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Returned when lending policy refuses a loan. Lists every violated rule
+// together with the policy that was applied.
+type PolicyError struct {
+	Policy     LendingPolicy     `json:"policy"`
+	Violations []PolicyViolation `json:"violations"`
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return "lending policy refused the loan: " + strings.Join(messages, "; ")
+}
+
+// Returns cost of lending a book for the given number of days.
+func (p LendingPolicy) LoanCost(pricePerDay float32, days int) float32 {
+	return pricePerDay * p.PriceMultiplier * float32(days)
+}
+
+// Query operations
+
+const policyColumns = "id, category_id, reader_type, loan_period, max_loans, max_renewals, price_multiplier, grace_days, created_at, updated_at"
+
+func (dt *LendingPolicy) scan(row interface{ Scan(...interface{}) error }) error {
+	return row.Scan(&dt.ID, &dt.CategoryID, &dt.ReaderType, &dt.LoanPeriod, &dt.MaxLoans, &dt.MaxRenewals, &dt.PriceMultiplier, &dt.GraceDays, &dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Gets a specific policy by id.
+func (dt *LendingPolicy) GetPolicy(db *sql.DB) error {
+	return dt.scan(db.QueryRow("SELECT "+policyColumns+" FROM lending_policies WHERE id=$1", dt.ID))
+}
+
+// Gets all stored policies, most specific first.
+func GetPolicies(db *sql.DB) ([]LendingPolicy, error) {
+	rows, err := db.Query("SELECT " + policyColumns + " FROM lending_policies ORDER BY (category_id IS NOT NULL) DESC, (reader_type <> '') DESC, created_at")
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	policies := []LendingPolicy{}
+
+	// Store query results into policies variable if no errors.
+	for rows.Next() {
+		var dt LendingPolicy
+		if err := dt.scan(rows); err != nil {
+			return nil, err
+		}
+		policies = append(policies, dt)
+	}
+
+	return policies, rows.Err()
+}
+
+// Returns the policy applying to a reader type and book. A policy for one
+// of the book's categories wins over a policy for the reader type only;
+// among equally specific policies the shortest loan period wins.
+func policyFor(q queryer, readerType string, bookID uuid.UUID) (LendingPolicy, error) {
+	var dt LendingPolicy
+	err := dt.scan(q.QueryRow("SELECT "+policyColumns+` FROM lending_policies
+		WHERE (category_id IS NULL OR category_id IN (SELECT categories_id FROM book_categories WHERE book_id=$1))
+		AND (reader_type = '' OR reader_type = $2)
+		ORDER BY (category_id IS NOT NULL) DESC, (reader_type <> '') DESC, loan_period
+		LIMIT 1`, bookID, readerType))
+	if err == sql.ErrNoRows {
+		return DefaultPolicy, nil
+	}
+	return dt, err
+}
+
+// Returns the policy applying to a loan of a book by a reader.
+func loanPolicy(q queryer, userID, bookID uuid.UUID) (LendingPolicy, error) {
+	var readerType string
+	if err := q.QueryRow("SELECT reader_type FROM users WHERE id=$1", userID).Scan(&readerType); err != nil {
+		return LendingPolicy{}, err
+	}
+	return policyFor(q, readerType, bookID)
+}
+
+// Gets the policy applying to a loan of a book by a reader.
+func GetLoanPolicy(db *sql.DB, userID, bookID uuid.UUID) (LendingPolicy, error) {
+	policy, err := loanPolicy(db, userID, bookID)
+	if err == sql.ErrNoRows {
+		return policy, ErrUserNotFound
+	}
+	return policy, err
+}
+
+// Checks a new loan against lending policy. An empty return date is set
+// to the end of the loan period. Returns *PolicyError listing every
+// violated rule.
+func (p LendingPolicy) checkLoan(q queryer, dt *Issue, now time.Time) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	latest := today.AddDate(0, 0, p.LoanPeriod)
+	if dt.ReturnDate == "" {
+		dt.ReturnDate = latest.Format(DateLayout)
+	}
+	due, err := time.ParseInLocation(DateLayout, dt.ReturnDate, time.Local)
+	if err != nil {
+		return ErrInvalidReturn
+	}
+
+	violations := []PolicyViolation{}
+	if due.Before(today) {
+		violations = append(violations, PolicyViolation{"return_date_past",
+			fmt.Sprintf("return date %s is in the past", dt.ReturnDate)})
+	}
+	if due.After(latest) {
+		violations = append(violations, PolicyViolation{"loan_period",
+			fmt.Sprintf("loan period is %d days, so the latest return date is %s", p.LoanPeriod, latest.Format(DateLayout))})
+	}
+	var loans int
+	if err := q.QueryRow("SELECT COUNT(*) FROM issue WHERE user_id=$1 AND closed_at IS NULL AND deleted_at IS NULL",
+		dt.UserID).Scan(&loans); err != nil {
+		return err
+	}
+	if loans >= p.MaxLoans {
+		violations = append(violations, PolicyViolation{"max_loans",
+			fmt.Sprintf("reader has %d open loans and may hold at most %d", loans, p.MaxLoans)})
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Policy: p, Violations: violations}
+	}
+	return nil
+}
+
+// CRUD operations
+
+func (dt *LendingPolicy) validate() error {
+	if dt.LoanPeriod <= 0 {
+		return errors.New("loanPeriod must be positive")
+	}
+	if dt.MaxLoans <= 0 {
+		return errors.New("maxLoans must be positive")
+	}
+	if dt.MaxRenewals < 0 {
+		return errors.New("maxRenewals cannot be negative")
+	}
+	if dt.PriceMultiplier <= 0 {
+		return errors.New("priceMultiplier must be positive")
+	}
+	if dt.GraceDays < 0 {
+		return errors.New("graceDays cannot be negative")
+	}
+	return nil
+}
+
+// Checks that no other policy has the same scope.
+func (dt *LendingPolicy) checkScope(tx *sql.Tx) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM lending_policies WHERE category_id IS NOT DISTINCT FROM $1 AND reader_type=$2 AND id<>$3)",
+		dt.CategoryID, dt.ReaderType, dt.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrPolicyExists
+	}
+	return nil
+}
+
+// Create new policy and insert to database.
+func (dt *LendingPolicy) CreatePolicy(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.checkScope(tx); err != nil {
+			return err
+		}
+		return dt.scan(tx.QueryRow(
+			"INSERT INTO lending_policies(category_id, reader_type, loan_period, max_loans, max_renewals, price_multiplier, grace_days, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "+policyColumns,
+			dt.CategoryID, dt.ReaderType, dt.LoanPeriod, dt.MaxLoans, dt.MaxRenewals, dt.PriceMultiplier, dt.GraceDays, timestamp, timestamp))
+	})
+}
+
+// Updates a specific policy by id.
+func (dt *LendingPolicy) UpdatePolicy(db *sql.DB) error {
+	if err := dt.validate(); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.checkScope(tx); err != nil {
+			return err
+		}
+		return dt.scan(tx.QueryRow(
+			"UPDATE lending_policies SET category_id=$1, reader_type=$2, loan_period=$3, max_loans=$4, max_renewals=$5, price_multiplier=$6, grace_days=$7, updated_at=$8 WHERE id=$9 RETURNING "+policyColumns,
+			dt.CategoryID, dt.ReaderType, dt.LoanPeriod, dt.MaxLoans, dt.MaxRenewals, dt.PriceMultiplier, dt.GraceDays, timestamp, dt.ID))
+	})
+}
+
+// Deletes a specific policy by id.
+func (dt *LendingPolicy) DeletePolicy(db *sql.DB) error {
+	res, err := db.Exec("DELETE FROM lending_policies WHERE id=$1", dt.ID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Loan types. Reading-room loans are for use in the library only and are
+// returned the day they are issued.
+const (
+	LoanHome        = "home"
+	LoanReadingRoom = "reading_room"
+)
+
+// Time of day the reading room closes, in HH:MM, on days its branch has no
+// opening hours. Reading-room loans still open after it are flagged.
+var ReadingRoomClosingTime = "20:00"
+
+var (
+	ErrLoanType          = errors.New("loanType must be home or reading_room")
+	ErrReadingRoomOnly   = errors.New("book is for the reading room only")
+	ErrReadingRoomReturn = errors.New("reading-room loans are returned the same day")
+)
+
+// Open reading-room loan not returned by closing time.
+type FlaggedLoan struct {
+	IssueID   uuid.UUID  `json:"issueID"`
+	UserID    uuid.UUID  `json:"userID"`
+	Firstname string     `json:"firstname"`
+	Surname   string     `json:"surname"`
+	Email     string     `json:"email"`
+	BookID    uuid.UUID  `json:"bookID"`
+	Title     string     `json:"title"`
+	BranchID  *uuid.UUID `json:"branchID"`
+	IssuedAt  time.Time  `json:"issuedAt"`
+	FlaggedAt time.Time  `json:"flaggedAt"`
+}
+
+// Query operations
+
+// Gets open reading-room loans flagged at closing time, longest flagged
+// first.
+func GetFlaggedLoans(db *sql.DB) ([]FlaggedLoan, error) {
+	rows, err := db.Query(`SELECT i.id, u.id, u.firstname, u.surname, u.email, i.book_id, b.name, i.branch_id, i.created_at, i.flagged_at
+		FROM issue i JOIN users u ON u.id = i.user_id JOIN book b ON b.id = i.book_id
+		WHERE i.flagged_at IS NOT NULL AND i.closed_at IS NULL AND i.deleted_at IS NULL
+		ORDER BY i.flagged_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := []FlaggedLoan{}
+	for rows.Next() {
+		var dt FlaggedLoan
+		if err := rows.Scan(&dt.IssueID, &dt.UserID, &dt.Firstname, &dt.Surname, &dt.Email, &dt.BookID, &dt.Title,
+			&dt.BranchID, &dt.IssuedAt, &dt.FlaggedAt); err != nil {
+			return nil, err
+		}
+		loans = append(loans, dt)
+	}
+	return loans, rows.Err()
+}
+
+// Flags open reading-room loans whose branch closed by now on the day they
+// were issued. Returns the number of loans flagged.
+func FlagReadingRoomLoans(db *sql.DB, now time.Time) (int64, error) {
+	var flagged int64
+	err := withTx(db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT id, branch_id, created_at FROM issue
+			WHERE loan_type=$1 AND flagged_at IS NULL AND closed_at IS NULL AND deleted_at IS NULL FOR UPDATE`, LoanReadingRoom)
+		if err != nil {
+			return err
+		}
+		loans := []Issue{}
+		for rows.Next() {
+			var dt Issue
+			if err := rows.Scan(&dt.ID, &dt.BranchID, &dt.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			loans = append(loans, dt)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		cache := calendars{}
+		for _, dt := range loans {
+			cal, err := cache.of(tx, dt.BranchID)
+			if err != nil {
+				return err
+			}
+			issued := time.Date(dt.CreatedAt.Year(), dt.CreatedAt.Month(), dt.CreatedAt.Day(), 0, 0, 0, 0, now.Location())
+			closing, err := cal.closingTime(issued, ReadingRoomClosingTime)
+			if err != nil {
+				return err
+			}
+			if now.Before(closing) {
+				continue
+			}
+			if _, err := tx.Exec("UPDATE issue SET flagged_at=$1, updated_at=$1 WHERE id=$2", now, dt.ID); err != nil {
+				return err
+			}
+			flagged++
+		}
+		return nil
+	})
+	return flagged, err
+}
+
+// Checks the loan type of an issue against its book. Books for the
+// reading room only can't be taken home, and reading-room loans are due
+// the day they were issued, which is also the default return date.
+func (dt *Issue) checkLoanType(q queryer, issued time.Time) error {
+	if dt.LoanType == "" {
+		dt.LoanType = LoanHome
+	}
+	if dt.LoanType != LoanHome && dt.LoanType != LoanReadingRoom {
+		return ErrLoanType
+	}
+	var readingRoomOnly bool
+	if err := q.QueryRow("SELECT reading_room_only FROM book WHERE id=$1", dt.BookID).Scan(&readingRoomOnly); err != nil {
+		return err
+	}
+	if readingRoomOnly && dt.LoanType != LoanReadingRoom {
+		return ErrReadingRoomOnly
+	}
+	if dt.LoanType != LoanReadingRoom {
+		return nil
+	}
+	today := issued.Format(DateLayout)
+	if dt.ReturnDate == "" {
+		dt.ReturnDate = today
+	}
+	if dt.ReturnDate != today {
+		return ErrReadingRoomReturn
+	}
+	return nil
+}
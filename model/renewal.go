@@ -0,0 +1,79 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Layout of issue return dates.
+const DateLayout = "2006-01-02"
+
+// Days past the return date after which a loan can't be renewed.
+var RenewalOverdueLimit = 7
+
+var (
+	ErrLoanClosed    = errors.New("loan is already closed")
+	ErrRenewalLimit  = errors.New("loan has reached the maximum number of renewals")
+	ErrLoanOverdue   = errors.New("loan is overdue beyond the renewal limit")
+	ErrBookOnHold    = errors.New("book is reserved by other readers")
+	ErrInvalidReturn = errors.New("returnDate must be a date in YYYY-MM-DD format")
+	ErrReturnBefore  = errors.New("returnDate can't be before the loan was issued")
+	ErrClientCost    = errors.New("costs and discounts are calculated by the server")
+)
+
+// Extends an open loan by the loan period of its lending policy and
+// recalculates its preliminary cost for the whole loan from the price
+// snapshot taken at issue time. A new return date on a day the branch is
+// closed moves to the next open day. Renewal is refused
+// when the loan reached the policy's renewal limit, is overdue by more than
+// RenewalOverdueLimit days or other readers wait for the book. Reading-room
+// loans can't be renewed.
+func (dt *Issue) RenewIssue(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT user_id, book_id, to_char(return_date, 'YYYY-MM-DD'), price_per_day, discount, renewals, loan_type, branch_id, closed_at, created_at FROM issue WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.ReturnDate, &dt.PricePerDay, &dt.Discount, &dt.Renewals, &dt.LoanType, &dt.BranchID, &dt.ClosedAt, &dt.CreatedAt); err != nil {
+			return err
+		}
+		if dt.ClosedAt != nil {
+			return ErrLoanClosed
+		}
+		if dt.LoanType == LoanReadingRoom {
+			return ErrReadingRoomReturn
+		}
+		due, err := time.ParseInLocation(DateLayout, dt.ReturnDate, time.Local)
+		if err != nil {
+			return ErrInvalidReturn
+		}
+		policy, err := loanPolicy(tx, dt.UserID, dt.BookID)
+		if err != nil {
+			return err
+		}
+		if dt.Renewals >= policy.MaxRenewals {
+			return ErrRenewalLimit
+		}
+		if timestamp.After(due.AddDate(0, 0, RenewalOverdueLimit+1)) {
+			return ErrLoanOverdue
+		}
+		var held bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM reservations WHERE book_id=$1 AND status=$2)",
+			dt.BookID, ReservationWaiting).Scan(&held); err != nil {
+			return err
+		}
+		if held {
+			return ErrBookOnHold
+		}
+
+		dt.ReturnDate = due.AddDate(0, 0, policy.LoanPeriod).Format(DateLayout)
+		if err := dt.rollReturnDate(tx); err != nil {
+			return err
+		}
+		dt.PremCostFunc(loanDays(dt.CreatedAt, dt.ReturnDate))
+		dt.Renewals++
+		dt.UpdatedAt = timestamp
+		_, err = tx.Exec("UPDATE issue SET return_date=$1, preliminary_cost=$2, renewals=$3, updated_at=$4 WHERE id=$5",
+			dt.ReturnDate, dt.PreliminaryCost, dt.Renewals, timestamp, dt.ID)
+		return err
+	})
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reservation statuses. Waiting and ready reservations are active.
+const (
+	ReservationWaiting   = "waiting"
+	ReservationReady     = "ready"
+	ReservationFulfilled = "fulfilled"
+	ReservationExpired   = "expired"
+	ReservationCancelled = "cancelled"
+)
+
+// Time a reader has to pick up a copy held for them.
+var HoldPickupPeriod = 3 * 24 * time.Hour
+
+var (
+	ErrAlreadyReserved   = errors.New("user already has an active reservation for the book")
+	ErrHoldAtOtherBranch = errors.New("the copy held for the reader is at another branch")
+)
+
+// Defines reservation model. A waiting reservation has a place in the
+// book's queue; a ready one holds a copy at a branch until the pickup
+// deadline.
+type Reservation struct {
+	ID             uuid.UUID  `json:"id"       sql:"uuid"`
+	UserID         uuid.UUID  `json:"userID" validate:"required" sql:"user_id"`
+	BookID         uuid.UUID  `json:"bookID" validate:"required" sql:"book_id"`
+	Status         string     `json:"status" sql:"status"`
+	PickupDeadline *time.Time `json:"pickupDeadline" sql:"pickup_deadline"`
+	BranchID       *uuid.UUID `json:"branchID" sql:"branch_id"`
+	Position       int        `json:"position,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt" sql:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" sql:"updated_at"`
+}
+
+// Query operations
+
+// Gets a specific reservation by id.
+func (dt *Reservation) GetReservation(db *sql.DB) error {
+	err := db.QueryRow("SELECT user_id, book_id, status, pickup_deadline, branch_id, created_at, updated_at FROM reservations WHERE id=$1",
+		dt.ID).Scan(&dt.UserID, &dt.BookID, &dt.Status, &dt.PickupDeadline, &dt.BranchID, &dt.CreatedAt, &dt.UpdatedAt)
+	if err != nil || dt.Status != ReservationWaiting {
+		return err
+	}
+	// Position counts waiting reservations made earlier, starting at 1.
+	return db.QueryRow("SELECT COUNT(*) + 1 FROM reservations WHERE book_id=$1 AND status=$2 AND created_at < $3",
+		dt.BookID, ReservationWaiting, dt.CreatedAt).Scan(&dt.Position)
+}
+
+// Gets active reservations of a book in queue order.
+func GetBookReservations(db *sql.DB, bookID uuid.UUID) ([]Reservation, error) {
+	rows, err := db.Query("SELECT id, user_id, book_id, status, pickup_deadline, branch_id, created_at, updated_at FROM reservations WHERE book_id=$1 AND status IN ($2, $3) ORDER BY created_at",
+		bookID, ReservationWaiting, ReservationReady)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	reservations := []Reservation{}
+	position := 0
+
+	// Store query results into reservations variable if no errors.
+	for rows.Next() {
+		var dt Reservation
+		if err := rows.Scan(&dt.ID, &dt.UserID, &dt.BookID, &dt.Status, &dt.PickupDeadline, &dt.BranchID, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if dt.Status == ReservationWaiting {
+			position++
+			dt.Position = position
+		}
+		reservations = append(reservations, dt)
+	}
+
+	return reservations, rows.Err()
+}
+
+// CRUD operations
+
+// Create new reservation and insert to database. The reservation joins the
+// end of the book's queue, or holds a copy at once if one is on the shelf
+// and nobody is waiting.
+func (dt *Reservation) CreateReservation(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := checkLendable(tx, dt.UserID, dt.BookID); err != nil {
+			return err
+		}
+		var reserved, waiting bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM reservations WHERE user_id=$1 AND book_id=$2 AND status IN ($3, $4))",
+			dt.UserID, dt.BookID, ReservationWaiting, ReservationReady).Scan(&reserved); err != nil {
+			return err
+		}
+		if reserved {
+			return ErrAlreadyReserved
+		}
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM reservations WHERE book_id=$1 AND status=$2)",
+			dt.BookID, ReservationWaiting).Scan(&waiting); err != nil {
+			return err
+		}
+
+		dt.Status, dt.PickupDeadline, dt.BranchID, dt.Position = ReservationWaiting, nil, nil, 0
+		if !waiting {
+			switch from, err := takeCopy(tx, dt.BookID, nil); err {
+			case nil:
+				deadline := timestamp.Add(HoldPickupPeriod)
+				dt.Status, dt.PickupDeadline, dt.BranchID = ReservationReady, &deadline, &from
+			case ErrNoCopiesAvailable:
+			default:
+				return err
+			}
+		}
+		if err := tx.QueryRow(
+			"INSERT INTO reservations(user_id, book_id, status, pickup_deadline, branch_id, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at",
+			dt.UserID, dt.BookID, dt.Status, dt.PickupDeadline, dt.BranchID, timestamp, timestamp).Scan(&dt.ID, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return err
+		}
+		if dt.Status == ReservationWaiting {
+			return tx.QueryRow("SELECT COUNT(*) FROM reservations WHERE book_id=$1 AND status=$2",
+				dt.BookID, ReservationWaiting).Scan(&dt.Position)
+		}
+		return nil
+	})
+}
+
+// Cancels an active reservation by id. A held copy goes to the next reader
+// in the queue.
+func (dt *Reservation) CancelReservation(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT book_id, status, branch_id FROM reservations WHERE id=$1 AND status IN ($2, $3) FOR UPDATE",
+			dt.ID, ReservationWaiting, ReservationReady).Scan(&dt.BookID, &dt.Status, &dt.BranchID); err != nil {
+			return err
+		}
+		held := dt.Status == ReservationReady
+		dt.Status = ReservationCancelled
+		if _, err := tx.Exec("UPDATE reservations SET status=$1, updated_at=$2 WHERE id=$3", dt.Status, timestamp, dt.ID); err != nil {
+			return err
+		}
+		if !held {
+			return nil
+		}
+		return releaseLoanCopy(tx, dt.BookID, dt.BranchID, timestamp)
+	})
+}
+
+// Expires ready reservations whose pickup deadline passed before now and
+// passes their copies on. Returns the number of expired reservations.
+func ExpireReservations(db *sql.DB, now time.Time) (int, error) {
+	expired := 0
+	err := withTx(db, func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id, book_id, branch_id FROM reservations WHERE status=$1 AND pickup_deadline < $2 ORDER BY pickup_deadline FOR UPDATE SKIP LOCKED",
+			ReservationReady, now)
+		if err != nil {
+			return err
+		}
+		holds := []Reservation{}
+		for rows.Next() {
+			var dt Reservation
+			if err := rows.Scan(&dt.ID, &dt.BookID, &dt.BranchID); err != nil {
+				rows.Close()
+				return err
+			}
+			holds = append(holds, dt)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, dt := range holds {
+			if _, err := tx.Exec("UPDATE reservations SET status=$1, updated_at=$2 WHERE id=$3", ReservationExpired, now, dt.ID); err != nil {
+				return err
+			}
+			if err := releaseLoanCopy(tx, dt.BookID, dt.BranchID, now); err != nil {
+				return err
+			}
+		}
+		expired = len(holds)
+		return nil
+	})
+	return expired, err
+}
+
+// Marks the reader's ready reservation of a book as fulfilled. Returns
+// true and the branch holding the copy if there was one, in which case its
+// held copy is used for the loan. A held copy is lent from the branch
+// holding it; a loan naming another branch gets ErrHoldAtOtherBranch.
+func pickUpHold(tx *sql.Tx, userID, bookID uuid.UUID, at time.Time) (bool, *uuid.UUID, error) {
+	var branchID *uuid.UUID
+	err := tx.QueryRow(`UPDATE reservations SET status=$1, updated_at=$2 WHERE id = (
+		SELECT id FROM reservations WHERE user_id=$3 AND book_id=$4 AND status=$5
+		ORDER BY created_at LIMIT 1 FOR UPDATE) RETURNING branch_id`, ReservationFulfilled, at, userID, bookID, ReservationReady).Scan(&branchID)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	return err == nil, branchID, err
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fine per day a book is kept past its return date and grace days.
+var LateFeePerDay float32 = 0.5
+
+// Surcharge per book condition on return, as a share of the book cost.
+var ConditionSurcharges = map[string]float32{
+	"good":    0,
+	"worn":    0.1,
+	"damaged": 0.5,
+}
+
+// Returned when a book condition has no surcharge defined.
+type UnknownConditionError struct {
+	Condition string
+}
+
+func (e *UnknownConditionError) Error() string {
+	known := make([]string, 0, len(ConditionSurcharges))
+	for condition := range ConditionSurcharges {
+		known = append(known, condition)
+	}
+	sort.Strings(known)
+	return fmt.Sprintf("unknown bookCondition %q, expected one of: %s", e.Condition, strings.Join(known, ", "))
+}
+
+// Returns late days and fine of a loan returned on the given day. A loan
+// due on a day the branch of the loan is closed is due the next open day,
+// and days the branch is closed are not late days.
+func lateFee(cal calendar, returnDate string, graceDays int, returned time.Time) (int, float32, error) {
+	due, err := time.ParseInLocation(DateLayout, returnDate, time.Local)
+	if err != nil {
+		return 0, 0, ErrInvalidReturn
+	}
+	lateFrom := cal.nextOpenDay(due).AddDate(0, 0, graceDays)
+	late := loanDays(lateFrom, returned.Format(DateLayout))
+	late -= cal.closedDays(lateFrom, late)
+	if late <= 0 {
+		return 0, 0, nil
+	}
+	return late, roundCents(float64(LateFeePerDay) * float64(late)), nil
+}
+
+func roundCents(amount float64) float32 {
+	return float32(math.Round(amount*100) / 100)
+}
+
+// Creates acceptance closing an open issue. The final cost is the price
+// snapshot for the days the book was kept less the reader discount, plus
+// late fees and the surcharge for the condition grade and damages found.
+// Discount holds the amount deducted by the reader discount and discount
+// rules, each of them explained in DiscountExplanation. The charges go to
+// the reader's ledger. The copy goes to the next reservation or back to
+// stock at the branch it is returned to.
+func (dt *Acceptance) ReturnIssue(db *sql.DB, issueID uuid.UUID) error {
+	if err := dt.assess(db); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		var loan Issue
+		if err := tx.QueryRow("SELECT user_id, book_id, to_char(return_date, 'YYYY-MM-DD'), price_per_day, discount, branch_id, closed_at, created_at FROM issue WHERE id=$1 AND deleted_at IS NULL FOR UPDATE",
+			issueID).Scan(&loan.UserID, &loan.BookID, &loan.ReturnDate, &loan.PricePerDay, &loan.Discount, &loan.BranchID, &loan.ClosedAt, &loan.CreatedAt); err != nil {
+			return err
+		}
+		if loan.ClosedAt != nil {
+			return ErrLoanClosed
+		}
+		// Books can be returned at any branch, the one lending it by default.
+		if dt.BranchID == nil {
+			dt.BranchID = loan.BranchID
+		}
+		branchID, err := resolveBranch(tx, dt.BranchID)
+		if err != nil {
+			return err
+		}
+		dt.BranchID = &branchID
+		policy, err := loanPolicy(tx, loan.UserID, loan.BookID)
+		if err != nil {
+			return err
+		}
+		var bookCost float32
+		if err := tx.QueryRow("SELECT cost FROM book WHERE id=$1", loan.BookID).Scan(&bookCost); err != nil {
+			return err
+		}
+
+		// A book returned on the day it was issued counts as kept one day.
+		dt.DaysKept = loanDays(loan.CreatedAt, timestamp.Format(DateLayout))
+		if dt.DaysKept < 1 {
+			dt.DaysKept = 1
+		}
+		rent := float64(loan.PricePerDay) * float64(dt.DaysKept)
+		dt.DiscountExplanation = DiscountExplanation{}
+		if loan.Discount > 0 {
+			dt.DiscountExplanation = append(dt.DiscountExplanation, AppliedDiscount{Name: "reader discount", Kind: DiscountReader,
+				Percent: loan.Discount, Amount: roundCents(rent * float64(loan.Discount) / 100), Reason: "discount of the reader when the book was issued"})
+		}
+		// Rules apply to the rent left after the reader discount.
+		rules, err := applyDiscountRules(tx, roundCents(rent*(1-float64(loan.Discount)/100)), loan.UserID, loan.BookID, timestamp)
+		if err != nil {
+			return err
+		}
+		dt.DiscountExplanation = append(dt.DiscountExplanation, rules...)
+		dt.Discount = 0
+		for _, applied := range dt.DiscountExplanation {
+			dt.Discount += applied.Amount
+		}
+		dt.Discount = roundCents(float64(dt.Discount))
+		cal, err := loadCalendar(tx, loan.BranchID)
+		if err != nil {
+			return err
+		}
+		if dt.LateDays, dt.LateFee, err = lateFee(cal, loan.ReturnDate, policy.GraceDays, timestamp); err != nil {
+			return err
+		}
+		dt.damageSurcharge(bookCost)
+		dt.FinalCost = roundCents(rent) - dt.Discount + dt.LateFee + dt.Surcharge
+		dt.UserID, dt.BookID, dt.IssueID = loan.UserID, loan.BookID, &issueID
+
+		if _, err := tx.Exec("UPDATE issue SET closed_at=$1, updated_at=$1 WHERE id=$2", timestamp, issueID); err != nil {
+			return err
+		}
+		if err := releaseCopy(tx, loan.BookID, branchID, timestamp); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(
+			"INSERT INTO acceptance(user_id, book_id, book_condition, discount, final_cost, photo, issue_id, days_kept, late_days, late_fee, surcharge, discount_explanation, branch_id, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id, created_at, updated_at",
+			dt.UserID, dt.BookID, dt.BookCondition, dt.Discount, dt.FinalCost, dt.Photo, dt.IssueID, dt.DaysKept, dt.LateDays, dt.LateFee, dt.Surcharge, dt.DiscountExplanation, dt.BranchID, timestamp, timestamp).Scan(&dt.ID, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+			return err
+		}
+		if err := dt.saveAssessment(tx); err != nil {
+			return err
+		}
+		return postReturnCharges(tx, dt, roundCents(rent))
+	})
+}
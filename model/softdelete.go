@@ -0,0 +1,88 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Marks a record in table as deleted. Returns sql.ErrNoRows if there is
+// no live record with the id.
+func softDelete(db queryer, table string, id uuid.UUID) error {
+	res, err := db.Exec(fmt.Sprintf("UPDATE %s SET deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL", table), time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// Clears the deleted mark of a record in table. Returns sql.ErrNoRows if
+// there is no deleted record with the id.
+func restore(db queryer, table string, id uuid.UUID) error {
+	res, err := db.Exec(fmt.Sprintf("UPDATE %s SET deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL", table), id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Purge queries in execution order. Lending history goes first; readers,
+// books and authors are only removed once nothing references them, and
+// authors not while a book that isn't deleted lists them. Paid
+// acceptances and readers with payments or ledger entries are kept as
+// financial records, books with transfers as stock records. Loans and
+// readers with lending overrides are kept as the audit trail.
+var purgeQueries = []struct {
+	table string
+	query string
+}{
+	{"acceptance", "DELETE FROM acceptance WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM payments WHERE payments.acceptance_id = acceptance.id)"},
+	{"issue", "DELETE FROM issue WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM acceptance WHERE acceptance.issue_id = issue.id) AND NOT EXISTS (SELECT 1 FROM lending_overrides WHERE lending_overrides.issue_id = issue.id)"},
+	{"users", "DELETE FROM users WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM issue WHERE issue.user_id = users.id) AND NOT EXISTS (SELECT 1 FROM acceptance WHERE acceptance.user_id = users.id) AND NOT EXISTS (SELECT 1 FROM payments WHERE payments.user_id = users.id) AND NOT EXISTS (SELECT 1 FROM ledger_entries WHERE ledger_entries.user_id = users.id) AND NOT EXISTS (SELECT 1 FROM lending_overrides WHERE lending_overrides.user_id = users.id)"},
+	{"book", "DELETE FROM book WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM issue WHERE issue.book_id = book.id) AND NOT EXISTS (SELECT 1 FROM acceptance WHERE acceptance.book_id = book.id) AND NOT EXISTS (SELECT 1 FROM transfers WHERE transfers.book_id = book.id)"},
+	{"authors", "DELETE FROM authors WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM book_authors JOIN book ON book.id = book_authors.book_id WHERE book_authors.author_id = authors.id AND book.deleted_at IS NULL)"},
+}
+
+// Hard deletes records that were soft deleted before the given time.
+// Returns the number of removed rows. A query that fails doesn't stop the
+// ones after it; the failures are returned together.
+func PurgeDeleted(db *sql.DB, before time.Time) (int64, error) {
+	var total int64
+	var failures []string
+	for _, purge := range purgeQueries {
+		n, err := purgeTable(db, purge.query, before)
+		if err != nil {
+			failures = append(failures, errors.Wrapf(err, "purge %s", purge.table).Error())
+			continue
+		}
+		total += n
+	}
+	if len(failures) > 0 {
+		return total, errors.New(strings.Join(failures, "; "))
+	}
+	return total, nil
+}
+
+// Runs a purge query and returns the number of removed rows.
+func purgeTable(db *sql.DB, query string, before time.Time) (int64, error) {
+	res, err := db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrBookNotFound      = errors.New("book not found")
+	ErrNoCopiesAvailable = errors.New("no copies of the book are available")
+)
+
+// Checks that reader and book of a loan exist. Both rows stay locked until
+// the transaction ends so they can't be deleted meanwhile. The reader row
+// is locked exclusively so loans of one reader are checked one at a time.
+func checkLendable(tx *sql.Tx, userID, bookID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRow("SELECT id FROM users WHERE id=$1 AND deleted_at IS NULL FOR NO KEY UPDATE", userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow("SELECT id FROM book WHERE id=$1 AND deleted_at IS NULL FOR SHARE", bookID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrBookNotFound
+	}
+	return err
+}
+
+// Takes one available copy of a book from stock at a branch, or at any
+// branch when branchID is nil, and returns the branch it came from, which
+// the loan records. Every stock row of the book is locked first, so
+// concurrent loans of the same book queue up and can't both take the last
+// copy. Returns ErrNoCopiesAvailable when none is left.
+func takeCopy(tx *sql.Tx, bookID uuid.UUID, branchID *uuid.UUID) (uuid.UUID, error) {
+	rows, err := tx.Query("SELECT id, branch_id, number_of_book FROM books WHERE book_id=$1 ORDER BY created_at FOR UPDATE", bookID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	var stockID, from uuid.UUID
+	found := false
+	for rows.Next() {
+		var id, branch uuid.UUID
+		var count int
+		if err := rows.Scan(&id, &branch, &count); err != nil {
+			return uuid.Nil, err
+		}
+		if count > 0 && !found && (branchID == nil || *branchID == branch) {
+			stockID, from, found = id, branch, true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, err
+	}
+	if !found {
+		return uuid.Nil, ErrNoCopiesAvailable
+	}
+	_, err = tx.Exec("UPDATE books SET number_of_book = number_of_book - 1 WHERE id=$1", stockID)
+	return from, err
+}
+
+// Puts a copy of a book back into stock at a branch. The branch gets a
+// stock row of its own if it had none for the book.
+func returnCopy(tx *sql.Tx, bookID, branchID uuid.UUID, at time.Time) error {
+	res, err := tx.Exec("UPDATE books SET number_of_book = number_of_book + 1 WHERE id = (SELECT id FROM books WHERE book_id=$1 AND branch_id=$2 ORDER BY created_at LIMIT 1)", bookID, branchID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO books(book_id, branch_id, number_of_book, created_at, deleted_at) VALUES($1, $2, 1, $3, $3)", bookID, branchID, at)
+	return err
+}
+
+// Passes a copy of a book returned at a branch on. The copy is held there
+// for the first waiting reservation, or put back into stock of the branch
+// if nobody is waiting.
+func releaseCopy(tx *sql.Tx, bookID, branchID uuid.UUID, at time.Time) error {
+	// Stock rows are locked first so releases of the same book queue up.
+	if _, err := tx.Exec("SELECT id FROM books WHERE book_id=$1 FOR UPDATE", bookID); err != nil {
+		return err
+	}
+	var id uuid.UUID
+	err := tx.QueryRow("SELECT id FROM reservations WHERE book_id=$1 AND status=$2 ORDER BY created_at LIMIT 1 FOR UPDATE",
+		bookID, ReservationWaiting).Scan(&id)
+	if err == sql.ErrNoRows {
+		return returnCopy(tx, bookID, branchID, at)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE reservations SET status=$1, pickup_deadline=$2, branch_id=$3, updated_at=$4 WHERE id=$5",
+		ReservationReady, at.Add(HoldPickupPeriod), branchID, at, id)
+	return err
+}
+
+// Passes on the copy of a loan that is no longer out, at the branch it
+// was lent from or the oldest branch for loans made before branches.
+func releaseLoanCopy(tx *sql.Tx, bookID uuid.UUID, branchID *uuid.UUID, at time.Time) error {
+	branch, err := resolveBranch(tx, branchID)
+	if err != nil {
+		return err
+	}
+	return releaseCopy(tx, bookID, branch, at)
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transfer statuses. A requested transfer has not left its branch yet; an
+// in transit one has taken its copies out of the source branch stock.
+const (
+	TransferRequested = "requested"
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+	TransferCancelled = "cancelled"
+)
+
+var (
+	ErrSameBranch     = errors.New("a transfer must move copies to another branch")
+	ErrTransferStatus = errors.New("transfer is not in a status that allows this")
+)
+
+// Defines transfer model: copies of a book moving between branches.
+type Transfer struct {
+	ID           uuid.UUID  `json:"id"       sql:"uuid"`
+	BookID       uuid.UUID  `json:"bookID" validate:"required" sql:"book_id"`
+	FromBranchID uuid.UUID  `json:"fromBranchID" validate:"required" sql:"from_branch_id"`
+	ToBranchID   uuid.UUID  `json:"toBranchID" validate:"required" sql:"to_branch_id"`
+	Quantity     int        `json:"quantity" validate:"required" sql:"quantity"`
+	Status       string     `json:"status" sql:"status"`
+	ShippedAt    *time.Time `json:"shippedAt" sql:"shipped_at"`
+	ReceivedAt   *time.Time `json:"receivedAt" sql:"received_at"`
+	CreatedAt    time.Time  `json:"createdAt" sql:"created_at"`
+	UpdatedAt    time.Time  `json:"updatedAt" sql:"updated_at"`
+}
+
+const transferColumns = "id, book_id, from_branch_id, to_branch_id, quantity, status, shipped_at, received_at, created_at, updated_at"
+
+func (dt *Transfer) scan(row interface{ Scan(...interface{}) error }) error {
+	return row.Scan(&dt.ID, &dt.BookID, &dt.FromBranchID, &dt.ToBranchID, &dt.Quantity, &dt.Status, &dt.ShippedAt, &dt.ReceivedAt, &dt.CreatedAt, &dt.UpdatedAt)
+}
+
+// Query operations
+
+// Gets a specific transfer by id.
+func (dt *Transfer) GetTransfer(db *sql.DB) error {
+	return dt.scan(db.QueryRow("SELECT "+transferColumns+" FROM transfers WHERE id=$1", dt.ID))
+}
+
+// Gets transfers newest first. Empty status lists every status and a nil
+// branchID lists transfers from or to every branch.
+func GetTransfers(db *sql.DB, status string, branchID *uuid.UUID) ([]Transfer, error) {
+	rows, err := db.Query("SELECT "+transferColumns+` FROM transfers
+		WHERE ($1 = '' OR status=$1) AND ($2::uuid IS NULL OR from_branch_id=$2 OR to_branch_id=$2)
+		ORDER BY created_at DESC`, status, branchID)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	transfers := []Transfer{}
+
+	// Store query results into transfers variable if no errors.
+	for rows.Next() {
+		var dt Transfer
+		if err := dt.scan(rows); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, dt)
+	}
+
+	return transfers, rows.Err()
+}
+
+// CRUD operations
+
+// Requests a transfer of copies of a book between two branches. Stock is
+// not touched until the transfer is shipped.
+func (dt *Transfer) CreateTransfer(db *sql.DB) error {
+	if dt.Quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	if dt.FromBranchID == dt.ToBranchID {
+		return ErrSameBranch
+	}
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM book WHERE id=$1 AND deleted_at IS NULL)", dt.BookID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrBookNotFound
+		}
+		for _, id := range []uuid.UUID{dt.FromBranchID, dt.ToBranchID} {
+			if _, err := resolveBranch(tx, &id); err != nil {
+				return err
+			}
+		}
+		return dt.scan(tx.QueryRow("INSERT INTO transfers(book_id, from_branch_id, to_branch_id, quantity, status, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING "+transferColumns,
+			dt.BookID, dt.FromBranchID, dt.ToBranchID, dt.Quantity, TransferRequested, timestamp, timestamp))
+	})
+}
+
+// Ships a requested transfer. Its copies leave the source branch stock and
+// are in transit until received; ErrNoCopiesAvailable is returned if the
+// branch doesn't have enough of them.
+func (dt *Transfer) ShipTransfer(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.lock(tx, TransferRequested); err != nil {
+			return err
+		}
+		for i := 0; i < dt.Quantity; i++ {
+			if _, err := takeCopy(tx, dt.BookID, &dt.FromBranchID); err != nil {
+				return err
+			}
+		}
+		return dt.scan(tx.QueryRow("UPDATE transfers SET status=$1, shipped_at=$2, updated_at=$2 WHERE id=$3 RETURNING "+transferColumns,
+			TransferInTransit, timestamp, dt.ID))
+	})
+}
+
+// Receives a transfer in transit at its destination. Each copy goes to the
+// next reservation waiting for the book or onto the branch shelves.
+func (dt *Transfer) ReceiveTransfer(db *sql.DB) error {
+	timestamp := time.Now()
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.lock(tx, TransferInTransit); err != nil {
+			return err
+		}
+		for i := 0; i < dt.Quantity; i++ {
+			if err := releaseCopy(tx, dt.BookID, dt.ToBranchID, timestamp); err != nil {
+				return err
+			}
+		}
+		return dt.scan(tx.QueryRow("UPDATE transfers SET status=$1, received_at=$2, updated_at=$2 WHERE id=$3 RETURNING "+transferColumns,
+			TransferReceived, timestamp, dt.ID))
+	})
+}
+
+// Cancels a transfer that has not been shipped yet.
+func (dt *Transfer) CancelTransfer(db *sql.DB) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		if err := dt.lock(tx, TransferRequested); err != nil {
+			return err
+		}
+		return dt.scan(tx.QueryRow("UPDATE transfers SET status=$1, updated_at=$2 WHERE id=$3 RETURNING "+transferColumns,
+			TransferCancelled, time.Now(), dt.ID))
+	})
+}
+
+// Locks the transfer and checks it is in the given status.
+func (dt *Transfer) lock(tx *sql.Tx, status string) error {
+	if err := dt.scan(tx.QueryRow("SELECT "+transferColumns+" FROM transfers WHERE id=$1 FOR UPDATE", dt.ID)); err != nil {
+		return err
+	}
+	if dt.Status != status {
+		return ErrTransferStatus
+	}
+	return nil
+}
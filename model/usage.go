@@ -0,0 +1,49 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// Number of times a book was lent in a period, by loan type.
+type BookUsage struct {
+	BookID           uuid.UUID `json:"bookID"`
+	Title            string    `json:"title"`
+	ReadingRoomOnly  bool      `json:"readingRoomOnly"`
+	HomeLoans        int       `json:"homeLoans"`
+	ReadingRoomLoans int       `json:"readingRoomLoans"`
+	TotalLoans       int       `json:"totalLoans"`
+}
+
+// Query operations
+
+// Gets usage of every book lent in the period from and to, both in
+// YYYY-MM-DD and optional, most used first. Loans are counted on the day
+// they were issued and reading-room loans count like any other.
+func GetBookUsage(db *sql.DB, from, to string) ([]BookUsage, error) {
+	start, end, err := periodBounds(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT b.id, b.name, b.reading_room_only,
+			COUNT(*) FILTER (WHERE i.loan_type = $1), COUNT(*) FILTER (WHERE i.loan_type = $2), COUNT(*)
+		FROM issue i JOIN book b ON b.id = i.book_id
+		WHERE i.deleted_at IS NULL AND i.created_at >= $3 AND i.created_at < $4
+		GROUP BY b.id, b.name, b.reading_room_only
+		ORDER BY COUNT(*) DESC, b.name`, LoanHome, LoanReadingRoom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []BookUsage{}
+	for rows.Next() {
+		var dt BookUsage
+		if err := rows.Scan(&dt.BookID, &dt.Title, &dt.ReadingRoomOnly, &dt.HomeLoans, &dt.ReadingRoomLoans, &dt.TotalLoans); err != nil {
+			return nil, err
+		}
+		usage = append(usage, dt)
+	}
+	return usage, rows.Err()
+}
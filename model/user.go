@@ -19,7 +19,9 @@ type User struct {
 	DateOfBirth    string    `json:"dateOfBirth" validate:"required" sql:"date_of_birth"`
 	Email          string    `json:"email" validate:"required" sql:"email"`
 	Address        string    `json:"address" validate:"required" sql:"address"`
-	Indebtedness   string    `json:"indebtedness" validate:"required" sql:"indebtedness"`
+	ReaderType     string    `json:"readerType" sql:"reader_type"`
+	Discount       float32   `json:"discount" sql:"discount"`
+	Balance        float32   `json:"balance"`
 	CreatedAt      time.Time `json:"createdAt" sql:"created_at"`
 	UpdatedAt      time.Time `json:"updatedAt" sql:"updated_at"`
 }
@@ -28,14 +30,14 @@ type User struct {
 
 // Gets a specific user by id.
 func (dt *User) GetUser(db *sql.DB) error {
-	return db.QueryRow("SELECT firstname, surname, second_name, passport, date_of_birth, email, address, indebtedness, created_at, updated_at FROM users WHERE id=$1",
-		dt.ID).Scan(&dt.Firstname, &dt.Surname, &dt.SecondName, &dt.Passport, &dt.DateOfBirth, &dt.Email, &dt.Address, &dt.Indebtedness, &dt.CreatedAt, &dt.UpdatedAt)
+	return db.QueryRow("SELECT firstname, surname, second_name, passport, date_of_birth, email, address, reader_type, discount, (SELECT COALESCE(ROUND(SUM(amount)::numeric, 2), 0)::float FROM ledger_entries WHERE user_id=users.id), created_at, updated_at FROM users WHERE id=$1 AND deleted_at IS NULL",
+		dt.ID).Scan(&dt.Firstname, &dt.Surname, &dt.SecondName, &dt.Passport, &dt.DateOfBirth, &dt.Email, &dt.Address, &dt.ReaderType, &dt.Discount, &dt.Balance, &dt.CreatedAt, &dt.UpdatedAt)
 }
 
 // Gets users. Limit count and start position in db.
 func GetUsers(db *sql.DB, field, sort string, limit, page int) ([]User, error) {
 
-	rows, err := db.Query(  "SELECT * FROM users ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
+	rows, err := db.Query(  "SELECT id, firstname, surname, second_name, passport, date_of_birth, email, address, reader_type, discount, (SELECT COALESCE(ROUND(SUM(amount)::numeric, 2), 0)::float FROM ledger_entries WHERE user_id=users.id), created_at, updated_at FROM users WHERE deleted_at IS NULL ORDER BY $1 ,$2 LIMIT $3 OFFSET $4",
 		field ,sort ,limit, limit*(page-1))
 
 	if err != nil {
@@ -49,7 +51,7 @@ func GetUsers(db *sql.DB, field, sort string, limit, page int) ([]User, error) {
 	// Store query results into user variable if no errors.
 	for rows.Next() {
 		var dt User
-		if err := rows.Scan(&dt.ID, &dt.Firstname, &dt.Surname, &dt.SecondName, &dt.Passport, &dt.DateOfBirth, &dt.Email, &dt.Address, &dt.Indebtedness, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
+		if err := rows.Scan(&dt.ID, &dt.Firstname, &dt.Surname, &dt.SecondName, &dt.Passport, &dt.DateOfBirth, &dt.Email, &dt.Address, &dt.ReaderType, &dt.Discount, &dt.Balance, &dt.CreatedAt, &dt.UpdatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, dt)
@@ -83,13 +85,16 @@ func (dt *User) CreateUser(db *sql.DB) error {
 	if dt.Address == "" {
 		return errors.New("address is required")
 	}
-	if dt.Indebtedness == "" {
-		return errors.New("indebtedness is required")
+	if dt.ReaderType == "" {
+		dt.ReaderType = DefaultReaderType
+	}
+	if dt.Discount < 0 || dt.Discount > 100 {
+		return errors.New("discount must be between 0 and 100 percent")
 	}
 	// Scan db after creation if user exists using new user id.
 	timestamp := time.Now()
 	err := db.QueryRow(
-		"INSERT INTO users(firstname, surname, second_name, passport, date_of_birth, email, address, indebtedness, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, firstname, surname, second_name, passport, date_of_birth, email, address, indebtedness, created_at, updated_at", dt.Firstname, dt.Surname, dt.SecondName, dt.Passport, dt.DateOfBirth, dt.Email, dt.Address, dt.Indebtedness, timestamp, timestamp).Scan(&dt.ID, &dt.Firstname, &dt.Surname, &dt.SecondName, &dt.Passport, &dt.DateOfBirth, &dt.Email, &dt.Address, &dt.Indebtedness, &dt.CreatedAt, &dt.UpdatedAt)
+		"INSERT INTO users(firstname, surname, second_name, passport, date_of_birth, email, address, reader_type, discount, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, firstname, surname, second_name, passport, date_of_birth, email, address, reader_type, discount, created_at, updated_at", dt.Firstname, dt.Surname, dt.SecondName, dt.Passport, dt.DateOfBirth, dt.Email, dt.Address, dt.ReaderType, dt.Discount, timestamp, timestamp).Scan(&dt.ID, &dt.Firstname, &dt.Surname, &dt.SecondName, &dt.Passport, &dt.DateOfBirth, &dt.Email, &dt.Address, &dt.ReaderType, &dt.Discount, &dt.CreatedAt, &dt.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -119,19 +124,24 @@ func (dt *User) UpdateUser(db *sql.DB) error {
 	if dt.Address == "" {
 		return errors.New("address is required")
 	}
-	if dt.Indebtedness == "" {
-		return errors.New("indebtedness is required")
+	if dt.ReaderType == "" {
+		dt.ReaderType = DefaultReaderType
+	}
+	if dt.Discount < 0 || dt.Discount > 100 {
+		return errors.New("discount must be between 0 and 100 percent")
 	}
 	timestamp := time.Now()
-	_, err :=
-		db.Exec("UPDATE users SET firstname=$1, surname=$2, second_name=$3, passport=$4, date_of_birth=$5, email=$6, address=$7, indebtedness=$8, updated_at=$9 WHERE id=$10 RETURNING id, firstname, surname, second_name, passport, date_of_birth, email, address, indebtedness, created_at, updated_at", dt.Firstname, dt.Surname, dt.SecondName, dt.Passport, dt.DateOfBirth, dt.Email, dt.Address, dt.Indebtedness, timestamp, dt.ID)
-
-	return err
+	// Deleted users are not found, so nothing is updated.
+	return db.QueryRow("UPDATE users SET firstname=$1, surname=$2, second_name=$3, passport=$4, date_of_birth=$5, email=$6, address=$7, reader_type=$8, discount=$9, updated_at=$10 WHERE id=$11 AND deleted_at IS NULL RETURNING created_at, updated_at",
+		dt.Firstname, dt.Surname, dt.SecondName, dt.Passport, dt.DateOfBirth, dt.Email, dt.Address, dt.ReaderType, dt.Discount, timestamp, dt.ID).Scan(&dt.CreatedAt, &dt.UpdatedAt)
 }
 
-// Deletes a specific user by id.
+// Soft deletes a specific user by id.
 func (dt *User) DeleteUser(db *sql.DB) error {
-	_, err := db.Exec("DELETE FROM users WHERE id=$1", dt.ID)
+	return softDelete(db, "users", dt.ID)
+}
 
-	return err
+// Restores a soft deleted user by id.
+func (dt *User) RestoreUser(db *sql.DB) error {
+	return restore(db, "users", dt.ID)
 }
\ No newline at end of file
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Versioned entities. Values match the table the record lives in.
+const (
+	VersionBook   = "book"
+	VersionAuthor = "authors"
+	VersionBooks  = "books"
+)
+
+// Defines snapshot of a catalog record.
+type Version struct {
+	ID        uuid.UUID       `json:"id"       sql:"uuid"`
+	Entity    string          `json:"entity" sql:"entity"`
+	RecordID  uuid.UUID       `json:"recordID" sql:"record_id"`
+	Version   int             `json:"version" sql:"version"`
+	Data      json.RawMessage `json:"data" sql:"data"`
+	ChangedBy string          `json:"changedBy" sql:"changed_by"`
+	CreatedAt time.Time       `json:"createdAt" sql:"created_at"`
+}
+
+// Defines single field difference between two versions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Query operations
+
+// Gets a specific version of a record.
+func (dt *Version) GetVersion(db *sql.DB) error {
+	return db.QueryRow("SELECT id, data, changed_by, created_at FROM record_versions WHERE entity=$1 AND record_id=$2 AND version=$3",
+		dt.Entity, dt.RecordID, dt.Version).Scan(&dt.ID, &dt.Data, &dt.ChangedBy, &dt.CreatedAt)
+}
+
+// Gets all versions of a record, oldest first.
+func GetVersions(db *sql.DB, entity string, recordID uuid.UUID) ([]Version, error) {
+	rows, err := db.Query("SELECT id, entity, record_id, version, data, changed_by, created_at FROM record_versions WHERE entity=$1 AND record_id=$2 ORDER BY version",
+		entity, recordID)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for query to execute then close the row.
+	defer rows.Close()
+
+	versions := []Version{}
+
+	// Store query results into versions variable if no errors.
+	for rows.Next() {
+		var dt Version
+		if err := rows.Scan(&dt.ID, &dt.Entity, &dt.RecordID, &dt.Version, &dt.Data, &dt.ChangedBy, &dt.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, dt)
+	}
+
+	return versions, rows.Err()
+}
+
+// Stores snapshots of an updated record. The state before the first
+// tracked update is stored as version 1 so the original values are kept.
+func recordVersion(q queryer, entity string, recordID uuid.UUID, previous, current interface{}, changedBy string) error {
+	var last int
+	if err := q.QueryRow("SELECT COALESCE(MAX(version), 0) FROM record_versions WHERE entity=$1 AND record_id=$2",
+		entity, recordID).Scan(&last); err != nil {
+		return err
+	}
+	if last == 0 {
+		if err := insertVersion(q, entity, recordID, 1, previous, ""); err != nil {
+			return err
+		}
+		last = 1
+	}
+	return insertVersion(q, entity, recordID, last+1, current, changedBy)
+}
+
+func insertVersion(q queryer, entity string, recordID uuid.UUID, version int, data interface{}, changedBy string) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec("INSERT INTO record_versions(entity, record_id, version, data, changed_by, created_at) VALUES($1, $2, $3, $4, $5, $6)",
+		entity, recordID, version, payload, changedBy, time.Now())
+	return err
+}
+
+// Decodes data of a stored version into dst.
+func loadVersion(db *sql.DB, entity string, recordID uuid.UUID, version int, dst interface{}) error {
+	v := Version{Entity: entity, RecordID: recordID, Version: version}
+	if err := v.GetVersion(db); err != nil {
+		return err
+	}
+	return json.Unmarshal(v.Data, dst)
+}
+
+// Compares data of two versions field by field.
+func DiffVersions(from, to Version) ([]FieldChange, error) {
+	var a, b map[string]interface{}
+	if err := json.Unmarshal(from.Data, &a); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to.Data, &b); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]bool{}
+	for k := range a {
+		fields[k] = true
+	}
+	for k := range b {
+		fields[k] = true
+	}
+
+	changes := []FieldChange{}
+	for k := range fields {
+		if !reflect.DeepEqual(a[k], b[k]) {
+			changes = append(changes, FieldChange{Field: k, From: a[k], To: b[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes, nil
+}
@@ -0,0 +1,111 @@
+// Package pdf writes simple text documents as PDF files. Text is set in
+// Helvetica, one of the standard fonts every PDF reader has, so no font
+// has to be embedded. The standard fonts only cover Latin-1, so any other
+// character, such as Ł or €, is written as "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margin in points.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 56
+)
+
+// Document is a PDF being written line by line from the top of the first
+// page. A new page is started when a line doesn't fit on the current one.
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// Creates an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// Writes a line of text in the given font size. Bold lines use the bold
+// Helvetica face. Characters outside Latin-1 are written as "?".
+func (d *Document) Line(size float64, bold bool, text string) {
+	lineHeight := size * 1.4
+	if len(d.pages) == 0 || d.y-lineHeight < margin {
+		d.pages = append(d.pages, &bytes.Buffer{})
+		d.y = pageHeight - margin
+	}
+	d.y -= lineHeight
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %.1f Tf %d %.1f Td (%s) Tj ET\n", font, size, margin, d.y, escape(text))
+}
+
+// Leaves an empty line of the given font size.
+func (d *Document) Space(size float64) {
+	d.Line(size, false, "")
+}
+
+// Returns the document as a PDF file.
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.Space(12)
+	}
+	var out bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	// Objects 1 to 4 are the catalog, page tree and fonts; each page
+	// follows as a page object and its content stream.
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// Escapes text for a PDF string. Characters outside Latin-1 can't be set
+// in the standard fonts and are replaced by a question mark.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Test writing a document longer than a page.
+// Tests if there is a page object per page and the file ends with the
+// cross-reference table.
+func TestDocument(t *testing.T) {
+	doc := New()
+	for i := 0; i < 100; i++ {
+		doc.Line(12, false, "Line")
+	}
+	out := doc.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("Expected a PDF file. Got %q", out[:20])
+	}
+	if pages := bytes.Count(out, []byte("/Type /Page ")); pages < 2 {
+		t.Errorf("Expected 100 lines to need more than one page. Got %d", pages)
+	}
+}
+
+// Test writing an empty document.
+// Tests if it still has one page.
+func TestEmptyDocument(t *testing.T) {
+	out := New().Bytes()
+	if pages := bytes.Count(out, []byte("/Type /Page ")); pages != 1 {
+		t.Errorf("Expected one page. Got %d", pages)
+	}
+}
+
+// Test escaping text for PDF strings.
+// Tests if parentheses are escaped, Latin-1 is written in octal and other
+// characters become a question mark.
+func TestEscape(t *testing.T) {
+	for _, c := range []struct {
+		text     string
+		expected string
+	}{
+		{"Line (with parentheses)", `Line \(with parentheses\)`},
+		{`back\slash`, `back\\slash`},
+		{"café", `caf\351`},
+		{"tab\there", "tab here"},
+		{"Łódź €5", "?\\363d? ?5"},
+	} {
+		if got := escape(c.text); got != c.expected {
+			t.Errorf("Expected %q for %q. Got %q", c.expected, c.text, got)
+		}
+	}
+}
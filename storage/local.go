@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files in a directory.
+type Local struct {
+	root string
+}
+
+// Creates local storage rooted at dir. The directory is created if missing.
+func NewLocal(dir string) (*Local, error) {
+	if dir == "" {
+		return nil, errors.New("storage: local directory is required")
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &Local{root: dir}, nil
+}
+
+// Returns file path of an object.
+func (s *Local) path(name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, name), nil
+}
+
+func (s *Local) Put(name string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see partial content.
+	tmp, err := ioutil.TempFile(s.root, ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Local) Get(name string) (*Object, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, ErrNotExist
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Object{ReadCloser: f, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *Local) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *Local) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
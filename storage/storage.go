@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrNotExist    = errors.New("storage: object does not exist")
+	ErrInvalidName = errors.New("storage: invalid object name")
+)
+
+// Defines stored object opened for reading.
+type Object struct {
+	io.ReadCloser
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage keeps uploaded files under flat names.
+type Storage interface {
+	// Stores content of r under name, replacing any existing object.
+	Put(name string, r io.Reader, size int64, contentType string) error
+	// Opens object for reading. Returns ErrNotExist if there is no such object.
+	Get(name string) (*Object, error)
+	// Removes object. Removing a missing object is not an error.
+	Delete(name string) error
+	// Lists names of all stored objects.
+	List() ([]string, error)
+}
+
+// Creates storage backend of the given kind ("local" or "s3") from config.
+func New(kind string) (Storage, error) {
+	switch kind {
+	case "", "local":
+		return NewLocal(viper.GetString("IMAGE_STORAGE_PATH"))
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  viper.GetString("S3_ENDPOINT"),
+			Region:    viper.GetString("S3_REGION"),
+			Bucket:    viper.GetString("S3_BUCKET"),
+			AccessKey: viper.GetString("S3_ACCESS_KEY"),
+			SecretKey: viper.GetString("S3_SECRET_KEY"),
+			UseSSL:    viper.GetBool("S3_USE_SSL"),
+		})
+	}
+	return nil, fmt.Errorf("storage: unknown backend %q", kind)
+}
+
+// Checks that name is a plain file name. Names with path separators or a
+// leading dot are rejected so objects can't escape the storage root.
+func checkName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\\x00") {
+		return ErrInvalidName
+	}
+	return nil
+}
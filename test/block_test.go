@@ -0,0 +1,165 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/app"
+	"github.com/library/model"
+)
+
+// Test issuing a book to a reader with an overdue loan.
+// Tests if status code = 403 with the overdue loan as the reason.
+func TestIssueBlockedByOverdueLoan(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(2)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET created_at=$1, return_date=$2 WHERE id=$3",
+		time.Now().AddDate(0, 0, -20), time.Now().AddDate(0, 0, -3).Format(model.DateLayout), loan.ID)
+
+	response = executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	reasons, _ := m["reasons"].([]interface{})
+	overdue, _ := m["overdueIssues"].([]interface{})
+	if len(reasons) != 1 || reasons[0].(map[string]interface{})["code"] != "overdue_loans" || len(overdue) != 1 || overdue[0] != loan.ID.String() {
+		t.Errorf("Expected an overdue_loans reason for issue %s. Got %v", loan.ID, m)
+	}
+}
+
+// Test issuing a book to a reader owing more than the debt limit.
+// Tests if status code = 403 until part of the debt is written off.
+func TestIssueBlockedByDebt(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	executeRequest(ledgerRequest(testID, model.ChargeDamage, model.MaxDebt+1))
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	if stock := stockOf(testID); stock != 1 {
+		t.Errorf("Expected the copy to stay in stock. Got %d", stock)
+	}
+
+	// Writing part of the debt off lifts the block.
+	executeRequest(ledgerRequest(testID, model.CreditWaiver, 2))
+	response = executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+}
+
+// Test overriding a lending block as an admin.
+// Tests if only a privileged admin can and the override is recorded.
+func TestOverrideLendingBlock(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	addAdmin(1)
+	executeRequest(ledgerRequest(testID, model.ChargeDamage, model.MaxDebt+1))
+	token, _ := app.GenerateAdminJWT(model.Admin{ID: uuid.MustParse(testID), Email: "testemail1@gmail.com"})
+
+	response := executeRequest(overrideRequest(token))
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	d.Database.Exec("UPDATE admins SET privileged=true WHERE id=$1", testID)
+	response = executeRequest(overrideRequest(token))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	if dt.Override == nil || dt.Override.AdminID.String() != testID || dt.Override.Block == nil {
+		t.Fatalf("Expected override by the admin with the lifted block. Got %v", dt.Override)
+	}
+
+	req, _ := http.NewRequest("GET", "/lending-overrides?userID="+testID, nil)
+	response = executeRequest(req)
+	var overrides []model.LendingOverride
+	json.Unmarshal(response.Body.Bytes(), &overrides)
+	if len(overrides) != 1 || overrides[0].IssueID != dt.ID || overrides[0].Block.Reasons[0].Code != "debt_limit" {
+		t.Errorf("Expected the override of the debt limit to be recorded. Got %v", overrides)
+	}
+}
+
+// Test purging a deleted loan made with an override.
+// Tests if the loan and its override are kept.
+func TestPurgeOverriddenLoan(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	addAdmin(1)
+	executeRequest(ledgerRequest(testID, model.ChargeDamage, model.MaxDebt+1))
+	d.Database.Exec("UPDATE admins SET privileged=true WHERE id=$1", testID)
+	token, _ := app.GenerateAdminJWT(model.Admin{ID: uuid.MustParse(testID), Email: "testemail1@gmail.com"})
+	response := executeRequest(overrideRequest(token))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+
+	timestamp := time.Now()
+	d.Database.Exec("UPDATE issue SET deleted_at=$1 WHERE id=$2", timestamp.AddDate(0, 0, -1), dt.ID)
+	if _, err := model.PurgeDeleted(d.Database, timestamp); err != nil {
+		t.Fatalf("Expected the purge to skip the loan. Got %v", err)
+	}
+	var overrides int
+	d.Database.QueryRow("SELECT count(*) FROM lending_overrides WHERE issue_id=$1 AND EXISTS (SELECT 1 FROM issue WHERE id=$1)", dt.ID).Scan(&overrides)
+	if overrides != 1 {
+		t.Errorf("Expected the loan to be kept with its override. Got %d overrides", overrides)
+	}
+}
+
+// Test moving a loan to a reader with too much debt.
+// Tests if the loan is refused like a new one and stays with the reader.
+func TestUpdateIssueToBlockedReader(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	reader := addReader()
+	executeRequest(ledgerRequest(reader, model.ChargeDamage, model.MaxDebt+1))
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     reader,
+		"bookID":     testID,
+		"returnDate": loan.ReturnDate,
+	})
+	req, _ := http.NewRequest("PUT", "/issue/"+loan.ID.String(), bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	var userID string
+	d.Database.QueryRow("SELECT user_id FROM issue WHERE id=$1", loan.ID).Scan(&userID)
+	if userID != testID {
+		t.Errorf("Expected the loan to stay with reader %s. Got %s", testID, userID)
+	}
+}
+
+func overrideRequest(token string) *http.Request {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     testID,
+		"bookID":     testID,
+		"returnDate": time.Now().AddDate(0, 0, 14).Format(model.DateLayout),
+		"override":   map[string]string{"reason": "reader settles the debt next week"},
+	})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Token", token)
+
+	return req
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -89,11 +90,12 @@ func TestCreateBook(t *testing.T) {
 		t.Error("Failed to generate token")
 	}
 
+	photo := addImage()
 	newData := model.Book{
 		Name: "string1",
 		Cost: 1.1,
 		PricePerDay: 1.5,
-		Photo: "string4",
+		Photo: photo,
 		YearOfPublishing: 1111,
 		NumberOfPages: 1222,
 	}
@@ -121,8 +123,8 @@ func TestCreateBook(t *testing.T) {
 	if m["pricePerDay"] != float64(2.2) {
 		t.Errorf("Expected book pricePerDay to be 2.2 Got '%v'", m["pricePerDay"])
 	}
-	if m["photo"] != "string4" {
-		t.Errorf("Expected book photo to be 'string4'. Got '%v'", m["photo"])
+	if m["photo"] != photo {
+		t.Errorf("Expected book photo to be '%s'. Got '%v'", photo, m["photo"])
 	}
 	if m["yearOfPublishing"] != uint(1111) {
 		t.Errorf("Expected book yearOfPublishing to be 1111. Got '%v'", m["yearOfPublishing"])
@@ -132,6 +134,29 @@ func TestCreateBook(t *testing.T) {
 	}
 }
 
+// Test creating a book with copies when there is no branch for them.
+// Tests if status code = 404 until a branch exists, then the copies are shelved there.
+func TestCreateBookCopiesNeedBranch(t *testing.T) {
+	clearTable()
+
+	payload, _ := json.Marshal(model.Book{Name: "string1", Cost: 1.1, PricePerDay: 1.5, Photo: addImage(), YearOfPublishing: 1111, NumberOfPages: 1222})
+	req, _ := http.NewRequest("POST", "/book?booksNumber=2", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	branch := addBranch("Main")
+	req, _ = http.NewRequest("POST", "/book?booksNumber=2", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var book model.Book
+	json.Unmarshal(response.Body.Bytes(), &book)
+	var copies int
+	d.Database.QueryRow("SELECT number_of_book FROM books WHERE book_id=$1 AND branch_id=$2", book.ID, branch).Scan(&copies)
+	if copies != 2 {
+		t.Errorf("Expected 2 copies at Main. Got %d", copies)
+	}
+}
+
 // Test process of updating a book.
 // Tests if status code = 200 & response contains JSON object with the updated contents.
 func TestUpdateBook(t *testing.T) {
@@ -149,7 +174,7 @@ func TestUpdateBook(t *testing.T) {
 	var originalBook map[string]interface{}
 	json.Unmarshal(response.Body.Bytes(), &originalBook)
 
-	var jsonStr = []byte(`{"name":"string1 - updated name", "categoryID":"uuid - updated category", "authorID":"uuid - updated author", "cost": 1.1 , "pricePerDay": 2.2, "photo":"string4 - updated photo" , "yearOfPublishing": 3333, "numberOfPages": 4444}`)
+	var jsonStr = []byte(`{"name":"string1 - updated name", "categoryID":"uuid - updated category", "authorID":"uuid - updated author", "cost": 1.1 , "pricePerDay": 2.2, "photo":"` + addImage() + `" , "yearOfPublishing": 3333, "numberOfPages": 4444}`)
 	req, _ = http.NewRequest("PUT", "/book/"+testID, bytes.NewBuffer(jsonStr))
 	// Add "Token" header to request with generated token.
 	req.Header.Add("Token", validToken)
@@ -216,6 +241,131 @@ func TestDeleteBook(t *testing.T) {
 	checkResponseCode(t, http.StatusNotFound, response.Code)
 }
 
+// Test change history of a book.
+// Tests if an update stores the original and the updated version.
+func TestBookVersions(t *testing.T) {
+	clearTable()
+	addBook(1)
+	// Generate JWT for authorization.
+	validToken, err := app.GenerateJWT()
+	if err != nil {
+		t.Error("Failed to generate token")
+	}
+	var jsonStr = []byte(`{"name":"string1 - updated name", "cost": 2.5, "pricePerDay": 0.5, "photo":"` + addImage() + `", "yearOfPublishing": 1999, "numberOfPages": 300}`)
+	req, _ := http.NewRequest("PUT", "/book/"+testID, bytes.NewBuffer(jsonStr))
+	// Add "Token" header to request with generated token.
+	req.Header.Add("Token", validToken)
+	req.Header.Set("Content-Type", "application/json")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/book/"+testID+"/versions", nil)
+	req.Header.Add("Token", validToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var versions []model.Version
+	json.Unmarshal(response.Body.Bytes(), &versions)
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions. Got %d", len(versions))
+	}
+
+	req, _ = http.NewRequest("GET", "/book/"+testID+"/versions/diff?from=1&to=2", nil)
+	req.Header.Add("Token", validToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var changes []model.FieldChange
+	json.Unmarshal(response.Body.Bytes(), &changes)
+	if len(changes) == 0 {
+		t.Errorf("Expected changes between versions 1 and 2. Got none")
+	}
+}
+
+// Test reverting a book to an earlier version.
+// Tests if only an admin can revert and the new version names them.
+func TestRevertBookVersion(t *testing.T) {
+	clearTable()
+	addBook(1)
+	addAdmin(1)
+	validToken, _ := app.GenerateJWT()
+	var jsonStr = []byte(`{"name":"string1 - updated name", "cost": 2.5, "pricePerDay": 0.5, "photo":"` + addImage() + `", "yearOfPublishing": 1999, "numberOfPages": 300}`)
+	req, _ := http.NewRequest("PUT", "/book/"+testID, bytes.NewBuffer(jsonStr))
+	req.Header.Add("Token", validToken)
+	req.Header.Set("Content-Type", "application/json")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("POST", "/book/"+testID+"/versions/1/revert", nil)
+	req.Header.Add("Token", validToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+
+	adminToken, _ := app.GenerateAdminJWT(model.Admin{ID: uuid.MustParse(testID), Email: "testemail1@gmail.com"})
+	req, _ = http.NewRequest("POST", "/book/"+testID+"/versions/1/revert", nil)
+	req.Header.Add("Token", adminToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/book/"+testID+"/versions", nil)
+	req.Header.Add("Token", adminToken)
+	response = executeRequest(req)
+	var versions []model.Version
+	json.Unmarshal(response.Body.Bytes(), &versions)
+	if len(versions) != 3 || versions[2].ChangedBy != "testemail1@gmail.com" {
+		t.Errorf("Expected a third version by the admin. Got %v", versions)
+	}
+}
+
+// Test purging deleted authors of books.
+// Tests if an author is only purged once no book that isn't deleted lists them.
+func TestPurgeAuthorOfLiveBook(t *testing.T) {
+	clearTable()
+	addBook(1)
+	author := uuid.NewString()
+	timestamp := time.Now()
+	d.Database.Exec("INSERT INTO authors(id, firstname, surname, date_of_birth, photo, created_at, updated_at, deleted_at) VALUES($1, $2, $3, $4, $5, $6, $6, $6)",
+		author, "string1", "string1", "string1", addImage(), timestamp.AddDate(0, 0, -1))
+	d.Database.Exec("INSERT INTO book_authors(book_id, author_id) VALUES($1, $2)", testID, author)
+
+	model.PurgeDeleted(d.Database, timestamp)
+	var exists bool
+	d.Database.QueryRow("SELECT EXISTS (SELECT 1 FROM authors WHERE id=$1)", author).Scan(&exists)
+	if !exists {
+		t.Fatal("Expected the author of a live book to be kept")
+	}
+
+	d.Database.Exec("UPDATE book SET deleted_at=$1 WHERE id=$2", timestamp.AddDate(0, 0, -1), testID)
+	model.PurgeDeleted(d.Database, timestamp)
+	d.Database.QueryRow("SELECT EXISTS (SELECT 1 FROM authors WHERE id=$1)", author).Scan(&exists)
+	if exists {
+		t.Error("Expected the author to be purged with the book")
+	}
+}
+
+// Test purging when one table can't be purged.
+// Tests if the failing table is reported and the tables after it are still purged.
+func TestPurgePastBlockedTable(t *testing.T) {
+	clearTable()
+	addBook(1)
+	d.Database.Exec("CREATE TABLE IF NOT EXISTS purge_blockers (book_id uuid REFERENCES book(id) ON DELETE RESTRICT)")
+	defer d.Database.Exec("DROP TABLE IF EXISTS purge_blockers")
+	d.Database.Exec("INSERT INTO purge_blockers(book_id) VALUES($1)", testID)
+	author := uuid.NewString()
+	timestamp := time.Now()
+	d.Database.Exec("INSERT INTO authors(id, firstname, surname, date_of_birth, photo, created_at, updated_at, deleted_at) VALUES($1, $2, $3, $4, $5, $6, $6, $6)",
+		author, "string1", "string1", "string1", addImage(), timestamp.AddDate(0, 0, -1))
+	d.Database.Exec("UPDATE book SET deleted_at=$1 WHERE id=$2", timestamp.AddDate(0, 0, -1), testID)
+
+	purged, err := model.PurgeDeleted(d.Database, timestamp)
+	if err == nil || !strings.Contains(err.Error(), "purge book") {
+		t.Errorf("Expected the book purge to be reported. Got %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected the author to be purged. Got %d purged records", purged)
+	}
+}
+
 // Helper functions
 
 // Adds 1 or more records to table for testing.
@@ -226,6 +376,6 @@ func addBook(count int) {
 
 	for i := 1; i <= count; i++ {
 		timestamp := time.Now()
-		d.Database.Exec("INSERT INTO book(id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)", testID, "string"+strconv.Itoa(i), i, i, "string"+strconv.Itoa(i), i, i, timestamp, timestamp)
+		d.Database.Exec("INSERT INTO book(id, name, cost, price_per_day, photo, year_of_publishing, number_of_pages, views, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", testID, "string"+strconv.Itoa(i), i, i, addImage(), i, i, 0, timestamp, timestamp)
 	}
 }
\ No newline at end of file
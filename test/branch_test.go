@@ -0,0 +1,251 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/model"
+)
+
+// Test creating a branch.
+// Tests if status code = 201 and the branch is listed.
+func TestCreateBranch(t *testing.T) {
+	clearTable()
+
+	payload := []byte(`{"name":"North","address":"1 North Street"}`)
+	req, _ := http.NewRequest("POST", "/branch", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	req, _ = http.NewRequest("GET", "/branches", nil)
+	response = executeRequest(req)
+	var branches []model.Branch
+	json.Unmarshal(response.Body.Bytes(), &branches)
+	if len(branches) != 1 || branches[0].Name != "North" {
+		t.Errorf("Expected branch North. Got %v", branches)
+	}
+}
+
+// Test returning a book at another branch than it was lent from.
+// Tests if the copy goes onto the shelves of the returning branch.
+func TestReturnAtAnotherBranch(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	north, south := addBranch("North"), addBranch("South")
+	addBranchStock(north, 1)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     testID,
+		"bookID":     testID,
+		"branchID":   north,
+		"returnDate": time.Now().AddDate(0, 0, 14).Format(model.DateLayout),
+	})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	if loan.BranchID == nil || loan.BranchID.String() != north {
+		t.Errorf("Expected the loan from North. Got %v", loan.BranchID)
+	}
+
+	payload, _ = json.Marshal(map[string]string{"bookCondition": "good", "photo": addImage(), "branchID": south})
+	req, _ = http.NewRequest("POST", "/issue/"+loan.ID.String()+"/return", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	stock := bookStock(t)
+	if stock[north].Available != 0 || stock[south].Available != 1 {
+		t.Errorf("Expected the copy on the South shelves. Got %v", stock)
+	}
+}
+
+// Test transferring copies between branches.
+// Tests if copies are in transit once shipped and at the destination once received.
+func TestTransferBetweenBranches(t *testing.T) {
+	clearTable()
+	addBook(1)
+	north, south := addBranch("North"), addBranch("South")
+	addBranchStock(north, 3)
+
+	payload, _ := json.Marshal(map[string]interface{}{"bookID": testID, "fromBranchID": north, "toBranchID": south, "quantity": 2})
+	req, _ := http.NewRequest("POST", "/transfer", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var transfer model.Transfer
+	json.Unmarshal(response.Body.Bytes(), &transfer)
+	if transfer.Status != model.TransferRequested {
+		t.Errorf("Expected status %s. Got %s", model.TransferRequested, transfer.Status)
+	}
+
+	// A transfer has to ship before it can be received.
+	req, _ = http.NewRequest("POST", "/transfer/"+transfer.ID.String()+"/receive", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, _ = http.NewRequest("POST", "/transfer/"+transfer.ID.String()+"/ship", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	stock := bookStock(t)
+	if stock[north].Available != 1 || stock[south].InTransit != 2 {
+		t.Errorf("Expected 1 copy left at North and 2 in transit to South. Got %v", stock)
+	}
+
+	req, _ = http.NewRequest("POST", "/transfer/"+transfer.ID.String()+"/receive", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	stock = bookStock(t)
+	if stock[south].Available != 2 || stock[south].InTransit != 0 {
+		t.Errorf("Expected 2 copies received at South. Got %v", stock)
+	}
+}
+
+// Test shipping a transfer of more copies than the branch has.
+// Tests if status code = 409 and the copies stay put.
+func TestShipTransferWithoutCopies(t *testing.T) {
+	clearTable()
+	addBook(1)
+	north, south := addBranch("North"), addBranch("South")
+	addBranchStock(north, 1)
+
+	payload, _ := json.Marshal(map[string]interface{}{"bookID": testID, "fromBranchID": north, "toBranchID": south, "quantity": 2})
+	req, _ := http.NewRequest("POST", "/transfer", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	var transfer model.Transfer
+	json.Unmarshal(response.Body.Bytes(), &transfer)
+
+	req, _ = http.NewRequest("POST", "/transfer/"+transfer.ID.String()+"/ship", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+	if stock := bookStock(t); stock[north].Available != 1 {
+		t.Errorf("Expected the copy to stay at North. Got %v", stock)
+	}
+}
+
+// Test purging a deleted book that was transferred between branches.
+// Tests if the book is kept and the purge goes on to remove deleted authors.
+func TestPurgeTransferredBook(t *testing.T) {
+	clearTable()
+	addBook(1)
+	north, south := addBranch("North"), addBranch("South")
+	addBranchStock(north, 1)
+	payload, _ := json.Marshal(map[string]interface{}{"bookID": testID, "fromBranchID": north, "toBranchID": south, "quantity": 1})
+	req, _ := http.NewRequest("POST", "/transfer", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	author := uuid.NewString()
+	timestamp := time.Now()
+	d.Database.Exec("INSERT INTO authors(id, firstname, surname, date_of_birth, photo, created_at, updated_at, deleted_at) VALUES($1, $2, $3, $4, $5, $6, $6, $6)",
+		author, "string1", "string1", "string1", addImage(), timestamp.AddDate(0, 0, -1))
+	d.Database.Exec("UPDATE book SET deleted_at=$1 WHERE id=$2", timestamp.AddDate(0, 0, -1), testID)
+
+	if _, err := model.PurgeDeleted(d.Database, timestamp); err != nil {
+		t.Fatalf("Expected the purge to skip the transferred book. Got %v", err)
+	}
+	var exists bool
+	d.Database.QueryRow("SELECT EXISTS (SELECT 1 FROM book WHERE id=$1)", testID).Scan(&exists)
+	if !exists {
+		t.Error("Expected the transferred book to be kept")
+	}
+	d.Database.QueryRow("SELECT EXISTS (SELECT 1 FROM authors WHERE id=$1)", author).Scan(&exists)
+	if exists {
+		t.Error("Expected the deleted author to be purged")
+	}
+}
+
+// Test deleting a branch a book is lent from.
+// Tests if status code = 409 until the book is returned.
+func TestDeleteBranchWithOpenLoan(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	// The book is returned to another branch, leaving its own branch empty.
+	south := addBranch("South")
+
+	req, _ := http.NewRequest("DELETE", "/branch/"+loan.BranchID.String(), nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	payload, _ := json.Marshal(map[string]string{"bookCondition": "good", "photo": addImage(), "branchID": south})
+	req, _ = http.NewRequest("POST", "/issue/"+loan.ID.String()+"/return", bytes.NewBuffer(payload))
+	executeRequest(req)
+
+	req, _ = http.NewRequest("DELETE", "/branch/"+loan.BranchID.String(), nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+// Test picking up a held copy while naming another branch.
+// Tests if status code = 409 and the loan is made at the holding branch.
+func TestIssueHoldAtOtherBranch(t *testing.T) {
+	clearTable()
+	addBook(1)
+	addStock(1)
+	reader := addReader()
+	hold := reserve(t, reader)
+	var heldAt string
+	d.Database.QueryRow("SELECT branch_id FROM reservations WHERE id=$1", hold.ID).Scan(&heldAt)
+	south := addBranch("South")
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     reader,
+		"bookID":     testID,
+		"branchID":   south,
+		"returnDate": time.Now().AddDate(0, 0, 14).Format(model.DateLayout),
+	})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	response = executeRequest(issueRequest(reader))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	if loan.BranchID == nil || loan.BranchID.String() != heldAt {
+		t.Errorf("Expected the loan at holding branch %s. Got %v", heldAt, loan.BranchID)
+	}
+}
+
+// Helper functions
+
+// Adds a branch, or finds it if it exists, and returns its id.
+func addBranch(name string) string {
+	var id string
+	timestamp := time.Now()
+	d.Database.QueryRow("INSERT INTO branches(name, created_at, updated_at) VALUES($1, $2, $2) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name RETURNING id",
+		name, timestamp).Scan(&id)
+
+	return id
+}
+
+// Adds stock of the test book at a branch.
+func addBranchStock(branchID string, count int) {
+	timestamp := time.Now()
+	d.Database.Exec("INSERT INTO books(book_id, branch_id, number_of_book, created_at, deleted_at) VALUES($1, $2, $3, $4, $5)", testID, branchID, count, timestamp, timestamp)
+}
+
+// Returns stock of the test book by branch id.
+func bookStock(t *testing.T) map[string]model.BranchStock {
+	req, _ := http.NewRequest("GET", "/book/"+testID+"/stock", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var stock []model.BranchStock
+	json.Unmarshal(response.Body.Bytes(), &stock)
+	byBranch := map[string]model.BranchStock{}
+	for _, v := range stock {
+		byBranch[v.BranchID.String()] = v
+	}
+
+	return byBranch
+}
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test issuing a book due on a holiday.
+// Tests if the return date moves to the next open day and a holiday can't be added twice.
+func TestDueDateRollsPastHolidays(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	due := time.Now().AddDate(0, 0, 14)
+	addHoliday(due)
+	addHoliday(due.AddDate(0, 0, 1))
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	if expected := due.AddDate(0, 0, 2).Format(model.DateLayout); loan.ReturnDate != expected {
+		t.Errorf("Expected return date %s after the holidays. Got %s", expected, loan.ReturnDate)
+	}
+
+	response = executeRequest(holidayRequest(due))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+// Test issuing a book due on a day the branch is closed.
+// Tests if the return date moves to the next opening day.
+func TestDueDateRollsPastClosedWeekdays(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	// Open Monday to Friday.
+	hours := []model.OpeningHours{}
+	for weekday := 1; weekday <= 5; weekday++ {
+		hours = append(hours, model.OpeningHours{Weekday: weekday, Opens: "09:00", Closes: "18:00"})
+	}
+	payload, _ := json.Marshal(hours)
+	req, _ := http.NewRequest("PUT", "/opening-hours", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	saturday := time.Now().AddDate(0, 0, 1)
+	for saturday.Weekday() != time.Saturday {
+		saturday = saturday.AddDate(0, 0, 1)
+	}
+	payload, _ = json.Marshal(map[string]interface{}{
+		"userID":     testID,
+		"bookID":     testID,
+		"returnDate": saturday.Format(model.DateLayout),
+	})
+	req, _ = http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	if expected := saturday.AddDate(0, 0, 2).Format(model.DateLayout); loan.ReturnDate != expected {
+		t.Errorf("Expected return date on Monday %s. Got %s", expected, loan.ReturnDate)
+	}
+}
+
+// Test setting invalid opening hours.
+// Tests if status code = 400, and 404 for a non-existent branch.
+func TestInvalidOpeningHours(t *testing.T) {
+	clearTable()
+
+	payload, _ := json.Marshal([]model.OpeningHours{{Weekday: 1, Opens: "18:00", Closes: "09:00"}})
+	req, _ := http.NewRequest("PUT", "/opening-hours", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("GET", "/opening-hours?branch="+testID, nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+// Test fines of an overdue loan spanning closed days.
+// Tests if only open days count as late.
+func TestNoFinesOnClosedDays(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	// No grace days under the default policy.
+	d.Database.Exec("UPDATE issue SET return_date=$1 WHERE id=$2", time.Now().AddDate(0, 0, -5).Format(model.DateLayout), loan.ID)
+	addHoliday(time.Now().AddDate(0, 0, -2))
+	addHoliday(time.Now().AddDate(0, 0, -1))
+
+	req, _ := http.NewRequest("GET", "/issuing/overdue", nil)
+	response = executeRequest(req)
+	var loans []model.OverdueLoan
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 1 || loans[0].DaysOverdue != 5 || loans[0].LateDays != 3 || loans[0].Fine != 3*model.LateFeePerDay {
+		t.Errorf("Expected 5 days overdue with 3 late days. Got %v", loans)
+	}
+}
+
+// Test fines of a loan due on a holiday added after it was issued.
+// Tests if lateness counts from the next open day.
+func TestNoFinesForDueDateOnHoliday(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET return_date=$1 WHERE id=$2", time.Now().AddDate(0, 0, -3).Format(model.DateLayout), loan.ID)
+	addHoliday(time.Now().AddDate(0, 0, -3))
+
+	req, _ := http.NewRequest("GET", "/issuing/overdue", nil)
+	response = executeRequest(req)
+	var loans []model.OverdueLoan
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 1 || loans[0].LateDays != 2 || loans[0].Fine != 2*model.LateFeePerDay {
+		t.Errorf("Expected 2 late days counted from the day after the holiday. Got %v", loans)
+	}
+
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	var acceptance model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &acceptance)
+	if acceptance.LateDays != 2 {
+		t.Errorf("Expected 2 late days on return. Got %d", acceptance.LateDays)
+	}
+}
+
+// Returns request for a library holiday on the day.
+func holidayRequest(day time.Time) *http.Request {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"date": day.Format(model.DateLayout),
+		"name": "Holiday",
+	})
+	req, _ := http.NewRequest("POST", "/holiday", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+// Closes the library on the day.
+func addHoliday(day time.Time) {
+	executeRequest(holidayRequest(day))
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test returning a book with damages and photos.
+// Tests if surcharges add up and the damages show in the book history.
+func TestReturnWithDamages(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	photos := []string{addImage(), addImage()}
+	response = executeRequest(damageRequest(loan.ID.String(), map[string]interface{}{
+		"bookCondition": "worn",
+		"damages": []map[string]string{
+			{"type": "water", "note": "cover soaked"},
+			{"type": "writing"},
+		},
+		"photos": photos,
+	}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	// Test book costs 1: worn 0.1, water 0.3 and writing 0.1.
+	if dt.Surcharge != 0.5 {
+		t.Errorf("Expected surcharge 0.5. Got %v", dt.Surcharge)
+	}
+	if dt.Photo != photos[0] || len(dt.Photos) != 2 {
+		t.Errorf("Expected both photos with the first as acceptance photo. Got %v, %v", dt.Photo, dt.Photos)
+	}
+
+	req, _ := http.NewRequest("GET", "/book/"+testID+"/damage-history", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var history []model.DamageRecord
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) != 1 || len(history[0].Damages) != 2 || len(history[0].Photos) != 2 {
+		t.Fatalf("Expected one return with two damages and two photos. Got %v", history)
+	}
+	if history[0].Damages[0].Type != "water" || history[0].Damages[0].Surcharge != 0.3 {
+		t.Errorf("Expected water damage with surcharge 0.3. Got %v", history[0].Damages[0])
+	}
+}
+
+// Test returning a book with an unknown damage type.
+// Tests if status code = 400.
+func TestReturnWithUnknownDamage(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	response = executeRequest(damageRequest(loan.ID.String(), map[string]interface{}{
+		"bookCondition": "good",
+		"damages":       []map[string]string{{"type": "fire"}},
+		"photos":        []string{addImage()},
+	}))
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
+
+// Test updating the photos of a return with damages.
+// Tests if the photos are replaced, the damages kept and both listed.
+func TestUpdateAcceptancePhotos(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	response = executeRequest(damageRequest(loan.ID.String(), map[string]interface{}{
+		"bookCondition": "worn",
+		"damages":       []map[string]string{{"type": "water"}},
+		"photos":        []string{addImage(), addImage()},
+	}))
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+
+	photo := addImage()
+	response = executeRequest(updateAcceptanceRequest(dt.ID.String(), map[string]interface{}{"bookCondition": "worn", "photos": []string{photo}}))
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ := http.NewRequest("GET", "/acceptances", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var acceptances []model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &acceptances)
+	if len(acceptances) != 1 {
+		t.Fatalf("Expected one acceptance. Got %d", len(acceptances))
+	}
+	if got := acceptances[0]; got.Photo != photo || len(got.Photos) != 1 || got.Photos[0] != photo {
+		t.Errorf("Expected photo %s only. Got %v, %v", photo, got.Photo, got.Photos)
+	}
+	if got := acceptances[0]; len(got.Damages) != 1 || got.Damages[0].Type != "water" {
+		t.Errorf("Expected the water damage to be kept. Got %v", got.Damages)
+	}
+}
+
+// Test updating the final cost of a return.
+// Tests if status code = 400 and the cost worked out on return is kept.
+func TestUpdateAcceptanceCost(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET created_at=$1 WHERE id=$2", time.Now().AddDate(0, 0, -10), loan.ID)
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+
+	response = executeRequest(updateAcceptanceRequest(dt.ID.String(), map[string]interface{}{"bookCondition": "good", "photo": dt.Photo, "finalCost": 0.01}))
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	response = executeRequest(updateAcceptanceRequest(dt.ID.String(), map[string]interface{}{"bookCondition": "good", "photo": dt.Photo, "userID": addReader()}))
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	response = executeRequest(updateAcceptanceRequest(dt.ID.String(), map[string]interface{}{"bookCondition": "good", "photo": dt.Photo}))
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var updated model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &updated)
+	if updated.FinalCost != dt.FinalCost || updated.UserID.String() != testID {
+		t.Errorf("Expected final cost %v of the test user. Got %v of %s", dt.FinalCost, updated.FinalCost, updated.UserID)
+	}
+}
+
+// Builds request updating an acceptance.
+func updateAcceptanceRequest(id string, acceptance map[string]interface{}) *http.Request {
+	payload, _ := json.Marshal(acceptance)
+	req, _ := http.NewRequest("PUT", "/acceptance/"+id, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+// Builds request returning a loan with an assessment.
+func damageRequest(issueID string, assessment map[string]interface{}) *http.Request {
+	payload, _ := json.Marshal(assessment)
+	req, _ := http.NewRequest("POST", "/issue/"+issueID+"/return", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
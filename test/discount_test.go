@@ -0,0 +1,148 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/model"
+)
+
+// Test returning a book under category, campaign and loyalty discount rules.
+// Tests if the matching stackable rules add up and are stored with the acceptance.
+func TestReturnWithDiscountRules(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	category := uuid.NewString()
+	d.Database.Exec("INSERT INTO categories(id, name, created_at) VALUES($1, $2, $3)", category, "classics", time.Now())
+	d.Database.Exec("INSERT INTO book_categories(book_id, categories_id) VALUES($1, $2)", testID, category)
+	today := time.Now().Format(model.DateLayout)
+	addDiscountRule(map[string]interface{}{"name": "classics month", "kind": model.DiscountCategory, "percent": 10, "categoryID": category, "stackable": true})
+	addDiscountRule(map[string]interface{}{"name": "spring sale", "kind": model.DiscountCampaign, "percent": 5, "startsOn": today, "endsOn": today, "stackable": true})
+	// The reader has no earlier loans, so the loyalty rule doesn't apply.
+	addDiscountRule(map[string]interface{}{"name": "loyal reader", "kind": model.DiscountLoyalty, "percent": 30, "minLoans": 1})
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET created_at=$1 WHERE id=$2", time.Now().AddDate(0, 0, -10), loan.ID)
+
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	// Rent is 10; stackable rules add up to 15%.
+	if dt.Discount != 1.5 || dt.FinalCost != 8.5 {
+		t.Errorf("Expected discount 1.5 and final cost 8.5. Got %v and %v", dt.Discount, dt.FinalCost)
+	}
+	if len(dt.DiscountExplanation) != 2 {
+		t.Errorf("Expected two applied discounts. Got %v", dt.DiscountExplanation)
+	}
+
+	// The explanation is stored with the acceptance.
+	req, _ := http.NewRequest("GET", "/acceptance/"+dt.ID.String(), nil)
+	response = executeRequest(req)
+	var stored model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &stored)
+	if len(stored.DiscountExplanation) != 2 || stored.DiscountExplanation[0].Amount+stored.DiscountExplanation[1].Amount != 1.5 {
+		t.Errorf("Expected stored explanation of 1.5 discount. Got %v", stored.DiscountExplanation)
+	}
+}
+
+// Test returning a book under a stackable and a larger non-stackable rule.
+// Tests if only the non-stackable rule is applied.
+func TestReturnWithExclusiveDiscount(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	today := time.Now().Format(model.DateLayout)
+	addDiscountRule(map[string]interface{}{"name": "spring sale", "kind": model.DiscountCampaign, "percent": 5, "startsOn": today, "endsOn": today, "stackable": true})
+	addDiscountRule(map[string]interface{}{"name": "library day", "kind": model.DiscountCampaign, "percent": 20, "startsOn": today, "endsOn": today})
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET created_at=$1 WHERE id=$2", time.Now().AddDate(0, 0, -10), loan.ID)
+
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	// A non-stackable rule larger than all stackable rules together wins alone.
+	if dt.Discount != 2 || len(dt.DiscountExplanation) != 1 || dt.DiscountExplanation[0].Name != "library day" {
+		t.Errorf("Expected only library day discount of 2. Got %v with %v", dt.Discount, dt.DiscountExplanation)
+	}
+}
+
+// Test creating discount rules with and without their required fields.
+// Tests if status code = 500 for a campaign without dates and new rules are active.
+func TestCreateInvalidDiscountRule(t *testing.T) {
+	clearTable()
+
+	response := executeRequest(discountRuleRequest(map[string]interface{}{"name": "summer", "kind": model.DiscountCampaign, "percent": 10}))
+	checkResponseCode(t, http.StatusInternalServerError, response.Code)
+
+	response = executeRequest(discountRuleRequest(map[string]interface{}{"name": "bulk", "kind": model.DiscountMultiBook, "percent": 10, "minBooks": 3}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var rule model.DiscountRule
+	json.Unmarshal(response.Body.Bytes(), &rule)
+	if rule.Active == nil || !*rule.Active || rule.Stackable {
+		t.Errorf("Expected an active, non-stackable rule. Got active %v, stackable %v", rule.Active, rule.Stackable)
+	}
+}
+
+// Test updating a discount rule with and without active.
+// Tests if a missing active keeps the current value and a given one replaces it.
+func TestUpdateDiscountRuleActive(t *testing.T) {
+	clearTable()
+
+	response := executeRequest(discountRuleRequest(map[string]interface{}{"name": "bulk", "kind": model.DiscountMultiBook, "percent": 10, "minBooks": 3, "active": false}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var rule model.DiscountRule
+	json.Unmarshal(response.Body.Bytes(), &rule)
+
+	response = executeRequest(updateDiscountRuleRequest(rule.ID.String(), map[string]interface{}{"name": "bulk", "kind": model.DiscountMultiBook, "percent": 15, "minBooks": 3}))
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var updated model.DiscountRule
+	json.Unmarshal(response.Body.Bytes(), &updated)
+	if updated.Active == nil || *updated.Active || updated.Percent != 15 {
+		t.Errorf("Expected an inactive rule of 15%%. Got active %v, percent %v", updated.Active, updated.Percent)
+	}
+
+	response = executeRequest(updateDiscountRuleRequest(rule.ID.String(), map[string]interface{}{"name": "bulk", "kind": model.DiscountMultiBook, "percent": 15, "minBooks": 3, "active": true}))
+	checkResponseCode(t, http.StatusOK, response.Code)
+	updated = model.DiscountRule{}
+	json.Unmarshal(response.Body.Bytes(), &updated)
+	if updated.Active == nil || !*updated.Active {
+		t.Errorf("Expected an active rule. Got active %v", updated.Active)
+	}
+}
+
+func discountRuleRequest(rule map[string]interface{}) *http.Request {
+	payload, _ := json.Marshal(rule)
+	req, _ := http.NewRequest("POST", "/discount-rule", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func updateDiscountRuleRequest(id string, rule map[string]interface{}) *http.Request {
+	payload, _ := json.Marshal(rule)
+	req, _ := http.NewRequest("PUT", "/discount-rule/"+id, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func addDiscountRule(rule map[string]interface{}) {
+	executeRequest(discountRuleRequest(rule))
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test getting the current loans and borrowing history of a reader.
+// Tests if open loans and returned ones are listed and filtered by date.
+func TestGetUserLoansAndHistory(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(2)
+
+	response := executeRequest(issueRequest(testID))
+	var returned model.Issue
+	json.Unmarshal(response.Body.Bytes(), &returned)
+	response = executeRequest(issueRequest(testID))
+	var open model.Issue
+	json.Unmarshal(response.Body.Bytes(), &open)
+	// The open loan was due yesterday.
+	yesterday := time.Now().AddDate(0, 0, -1).Format(model.DateLayout)
+	d.Database.Exec("UPDATE issue SET return_date=$1 WHERE id=$2", yesterday, open.ID)
+
+	response = executeRequest(returnRequest(returned.ID.String(), "good"))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	req, _ := http.NewRequest("GET", "/user/"+testID+"/loans", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var loans []model.Loan
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 1 || loans[0].IssueID != open.ID {
+		t.Fatalf("Expected only the open loan. Got %v", loans)
+	}
+	if !loans[0].Overdue || loans[0].DaysOverdue != 1 || loans[0].Title == "" {
+		t.Errorf("Expected the loan overdue by 1 day with its title. Got %v", loans[0])
+	}
+
+	req, _ = http.NewRequest("GET", "/user/"+testID+"/history", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var history []model.HistoryEntry
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) != 1 || history[0].IssueID != returned.ID || history[0].AcceptanceID == nil {
+		t.Fatalf("Expected the returned loan with its acceptance. Got %v", history)
+	}
+
+	// Loans issued after tomorrow are filtered out.
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(model.DateLayout)
+	req, _ = http.NewRequest("GET", "/user/"+testID+"/loans?from="+tomorrow, nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 0 {
+		t.Errorf("Expected no loans issued from tomorrow. Got %v", loans)
+	}
+
+	req, _ = http.NewRequest("GET", "/user/"+testID+"/history?from=yesterday", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
+
+// Test paging through the loans of a reader.
+// Tests if each page holds no more than the limit.
+func TestGetUserLoansPagination(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(3)
+
+	for i := 0; i < 3; i++ {
+		executeRequest(issueRequest(testID))
+	}
+
+	req, _ := http.NewRequest("GET", "/user/"+testID+"/loans?limit=2&page=2", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var loans []model.Loan
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 1 {
+		t.Errorf("Expected 1 loan on the second page. Got %d", len(loans))
+	}
+}
+
+// Test getting the loans of a non-existent reader.
+// Tests if status code = 404.
+func TestGetLoansOfNonExistentUser(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("GET", "/user/"+testID+"/loans", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
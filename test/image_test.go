@@ -0,0 +1,226 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/app"
+	"github.com/library/model"
+)
+
+// Test functions
+
+// Test creating a book whose photo is not an uploaded image.
+// Tests if request is rejected.
+func TestCreateBookWithUnknownImage(t *testing.T) {
+	clearTable()
+	// Generate JWT for authorization.
+	validToken, err := app.GenerateJWT()
+	if err != nil {
+		t.Error("Failed to generate token")
+	}
+
+	newData := model.Book{
+		Name:             "string1",
+		Cost:             1.1,
+		PricePerDay:      1.5,
+		Photo:            uuid.NewString(),
+		YearOfPublishing: 1111,
+		NumberOfPages:    1222,
+	}
+	payload, err := json.Marshal(newData)
+	if err != nil {
+		t.Error("Failed to parse JSON")
+	}
+	req, _ := http.NewRequest("POST", "/book", bytes.NewBuffer(payload))
+	// Add "Token" header to request with generated token.
+	req.Header.Add("Token", validToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, response.Code)
+
+	var m map[string]string
+	json.Unmarshal(response.Body.Bytes(), &m)
+	if m["error"] != model.ErrImageNotFound.Error() {
+		t.Errorf("Expected the 'error' key of the response to be set to '%s'. Got '%s'", model.ErrImageNotFound, m["error"])
+	}
+}
+
+// Test updating book photos given as an upper-case ID and as a file name.
+// Tests if the ID is stored in canonical form and a legacy file name is
+// kept only while it is the current photo.
+func TestUpdateBookPhoto(t *testing.T) {
+	clearTable()
+	addBook(1)
+	d.Database.Exec("UPDATE book SET photo='cover.png' WHERE id=$1", testID)
+	validToken, err := app.GenerateJWT()
+	if err != nil {
+		t.Error("Failed to generate token")
+	}
+
+	for _, c := range []struct {
+		photo    string
+		code     int
+		expected string
+	}{
+		{"cover.png", http.StatusOK, "cover.png"},
+		{"other.png", http.StatusInternalServerError, "cover.png"},
+		{strings.ToUpper(addImage()), http.StatusOK, ""},
+	} {
+		payload, _ := json.Marshal(model.Book{Name: "string1", Cost: 1, PricePerDay: 1, Photo: c.photo, YearOfPublishing: 1, NumberOfPages: 1})
+		req, _ := http.NewRequest("PUT", "/book/"+testID, bytes.NewBuffer(payload))
+		req.Header.Add("Token", validToken)
+		req.Header.Set("Content-Type", "application/json")
+		response := executeRequest(req)
+		checkResponseCode(t, c.code, response.Code)
+
+		var photo string
+		d.Database.QueryRow("SELECT photo FROM book WHERE id=$1", testID).Scan(&photo)
+		if c.expected == "" {
+			c.expected = strings.ToLower(c.photo)
+		}
+		if photo != c.expected {
+			t.Errorf("Expected photo '%s' after updating to '%s'. Got '%s'", c.expected, c.photo, photo)
+		}
+	}
+}
+
+// Test uploading the same image twice.
+// Tests if both uploads share one stored file.
+func TestUploadDuplicateImage(t *testing.T) {
+	clearTable()
+	var content bytes.Buffer
+	png.Encode(&content, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	first := uploadImage(t, content.Bytes())
+	second := uploadImage(t, content.Bytes())
+
+	if first.ID == second.ID {
+		t.Errorf("Expected uploads to get different IDs. Got '%s' twice", first.ID)
+	}
+	if first.SHA256 == "" || first.SHA256 != second.SHA256 {
+		t.Errorf("Expected uploads to have the same hash. Got '%s' and '%s'", first.SHA256, second.SHA256)
+	}
+	if first.Filename != second.Filename {
+		t.Errorf("Expected uploads to share a file. Got '%s' and '%s'", first.Filename, second.Filename)
+	}
+
+	var refCount int
+	d.Database.QueryRow("SELECT ref_count FROM image_blobs WHERE sha256=$1", first.SHA256).Scan(&refCount)
+	if refCount != 2 {
+		t.Errorf("Expected stored content to have 2 references. Got %d", refCount)
+	}
+}
+
+// Test uploading a small PNG that declares a huge size.
+// Tests if status code = 400 and nothing is stored.
+func TestUploadDecompressionBomb(t *testing.T) {
+	clearTable()
+	var content bytes.Buffer
+	png.Encode(&content, image.NewGray(image.Rect(0, 0, 1, 1)))
+	bomb := content.Bytes()
+	// IHDR data follows the 8 byte signature and the chunk length and type.
+	binary.BigEndian.PutUint32(bomb[16:], 100000)
+	binary.BigEndian.PutUint32(bomb[20:], 100000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "bomb.png")
+	part.Write(bomb)
+	form.Close()
+	req, _ := http.NewRequest("POST", "/post/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	var count int
+	d.Database.QueryRow("SELECT count(*) FROM images").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected no stored images. Got %d", count)
+	}
+}
+
+// Test loading an image with a Range request and a known ETag.
+// Tests if partial content and 304 are returned with the image headers.
+func TestLoadImage(t *testing.T) {
+	clearTable()
+	var content bytes.Buffer
+	png.Encode(&content, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	uploaded := uploadImage(t, content.Bytes())
+
+	req, _ := http.NewRequest("GET", "/load/image?image="+uploaded.ID.String(), nil)
+	req.Header.Set("Range", "bytes=0-3")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, response.Code)
+
+	if ct := response.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected Content-Type 'image/png'. Got '%s'", ct)
+	}
+	if !bytes.Equal(response.Body.Bytes(), content.Bytes()[:4]) {
+		t.Errorf("Expected first 4 bytes of the image. Got %v", response.Body.Bytes())
+	}
+	etag := response.Header().Get("ETag")
+	if etag == "" || response.Header().Get("Last-Modified") == "" {
+		t.Error("Expected ETag and Last-Modified headers to be set")
+	}
+
+	req, _ = http.NewRequest("GET", "/load/image?image="+uploaded.ID.String(), nil)
+	req.Header.Set("If-None-Match", etag)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotModified, response.Code)
+}
+
+// Test loading files that are not stored images.
+// Tests if 404 is returned for unknown IDs and paths outside the storage.
+func TestLoadMissingImage(t *testing.T) {
+	clearTable()
+	for _, name := range []string{uuid.NewString(), "missing.png", "../config.yaml", "..%2Fconfig.yaml"} {
+		req, _ := http.NewRequest("GET", "/load/image?image="+name, nil)
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	}
+}
+
+// Helper functions
+
+// Uploads image content and returns the stored image.
+func uploadImage(t *testing.T, content []byte) model.Image {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "cover.png")
+	part.Write(content)
+	form.Close()
+
+	req, _ := http.NewRequest("POST", "/post/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var uploaded []model.Image
+	json.Unmarshal(response.Body.Bytes(), &uploaded)
+	if len(uploaded) != 1 {
+		t.Fatalf("Expected one uploaded image. Got %d", len(uploaded))
+	}
+	return uploaded[0]
+}
+
+// Adds image record for testing and returns its id.
+func addImage() string {
+	id := uuid.NewString()
+	d.Database.Exec("INSERT INTO images(id, filename, original_name, content_type, size, width, height, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)", id, id+".png", "cover.png", "image/png", 1, 1, 1, time.Now())
+
+	return id
+}
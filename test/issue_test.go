@@ -0,0 +1,387 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/model"
+)
+
+// Test functions
+
+// Test issuing a book that has no copies left.
+// Tests if status code = 409 and no issue is stored.
+func TestCreateIssueWithoutCopies(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(0)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	if count := countIssues(); count != 0 {
+		t.Errorf("Expected no issues to be stored. Got %d", count)
+	}
+}
+
+// Test issuing a book to a reader that doesn't exist.
+// Tests if status code = 404 and stock is unchanged.
+func TestCreateIssueForUnknownUser(t *testing.T) {
+	clearTable()
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(uuid.NewString()))
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	if stock := stockOf(testID); stock != 1 {
+		t.Errorf("Expected stock to stay 1. Got %d", stock)
+	}
+}
+
+// Test issuing the same book from many concurrent requests.
+// Tests if no more copies are lent than there are in stock.
+func TestConcurrentIssues(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(3)
+
+	const requests = 10
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- executeRequest(issueRequest(testID)).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created, conflicts := 0, 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+			conflicts++
+		default:
+			t.Errorf("Unexpected response code %d", code)
+		}
+	}
+	if created != 3 || conflicts != requests-3 {
+		t.Errorf("Expected 3 issues and %d conflicts. Got %d and %d", requests-3, created, conflicts)
+	}
+	if count := countIssues(); count != 3 {
+		t.Errorf("Expected 3 stored issues. Got %d", count)
+	}
+	if stock := stockOf(testID); stock != 0 {
+		t.Errorf("Expected stock to be 0. Got %d", stock)
+	}
+}
+
+// Test deleting an open issue.
+// Tests if the copy is returned to stock.
+func TestDeleteIssueRestocks(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+
+	req, _ := http.NewRequest("DELETE", "/issue/"+dt.ID.String(), nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	if stock := stockOf(testID); stock != 1 {
+		t.Errorf("Expected stock to be 1. Got %d", stock)
+	}
+}
+
+// Test issuing a book with a cost chosen by the client.
+// Tests if status code = 400.
+func TestCreateIssueWithClientCost(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	payload, _ := json.Marshal(map[string]interface{}{"userID": testID, "bookID": testID, "preliminaryCost": 0.01})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	var m map[string]string
+	json.Unmarshal(response.Body.Bytes(), &m)
+	if m["error"] != model.ErrClientCost.Error() {
+		t.Errorf("Expected the 'error' key of the response to be set to '%s'. Got '%s'", model.ErrClientCost, m["error"])
+	}
+}
+
+// Test issuing a book to a reader with a discount.
+// Tests if the cost is calculated from the book price and the discount is snapshotted.
+func TestCreateIssueWithDiscount(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	d.Database.Exec("UPDATE users SET discount=25 WHERE id=$1", testID)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+
+	// Test book costs 1 per day and issueRequest asks for 14 days.
+	if dt.PricePerDay != 1 || dt.Discount != 25 || dt.PreliminaryCost != 10.5 {
+		t.Errorf("Expected price 1, discount 25 and cost 10.5. Got %v, %v and %v", dt.PricePerDay, dt.Discount, dt.PreliminaryCost)
+	}
+
+	// Later price changes don't touch the loan.
+	d.Database.Exec("UPDATE book SET price_per_day=5 WHERE id=$1", testID)
+	req, _ := http.NewRequest("GET", "/issue/"+dt.ID.String(), nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	if dt.PreliminaryCost != 10.5 {
+		t.Errorf("Expected cost to stay 10.5. Got %v", dt.PreliminaryCost)
+	}
+}
+
+// Test renewing a loan until the renewal limit.
+// Tests if return date moves by the renewal period and the limit is enforced.
+func TestRenewIssue(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+
+	due, _ := time.Parse(model.DateLayout, dt.ReturnDate)
+	for i := 1; i <= model.DefaultPolicy.MaxRenewals; i++ {
+		req, _ := http.NewRequest("POST", "/issue/"+dt.ID.String()+"/renew", nil)
+		response = executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var renewed model.Issue
+		json.Unmarshal(response.Body.Bytes(), &renewed)
+		due = due.AddDate(0, 0, model.DefaultPolicy.LoanPeriod)
+		if renewed.ReturnDate != due.Format(model.DateLayout) || renewed.Renewals != i {
+			t.Errorf("Expected return date %s after %d renewals. Got %s after %d", due.Format(model.DateLayout), i, renewed.ReturnDate, renewed.Renewals)
+		}
+		if renewed.PreliminaryCost <= dt.PreliminaryCost {
+			t.Errorf("Expected preliminary cost to grow. Got %v", renewed.PreliminaryCost)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/issue/"+dt.ID.String()+"/renew", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+// Test renewing a loan of a book other readers are waiting for.
+// Tests if status code = 409.
+func TestRenewIssueWithHolds(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	reserve(t, addReader())
+
+	req, _ := http.NewRequest("POST", "/issue/"+dt.ID.String()+"/renew", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	var m map[string]string
+	json.Unmarshal(response.Body.Bytes(), &m)
+	if m["error"] != model.ErrBookOnHold.Error() {
+		t.Errorf("Expected the 'error' key of the response to be set to '%s'. Got '%s'", model.ErrBookOnHold, m["error"])
+	}
+}
+
+// Test returning a book kept past its return date.
+// Tests if acceptance is created from the loan with rent, late fee and surcharge.
+func TestReturnIssue(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	// Move the loan into the past: issued 20 days ago, due 5 days ago.
+	d.Database.Exec("UPDATE issue SET created_at=$1, return_date=$2 WHERE id=$3",
+		time.Now().AddDate(0, 0, -20), time.Now().AddDate(0, 0, -5).Format(model.DateLayout), loan.ID)
+
+	response = executeRequest(returnRequest(loan.ID.String(), "worn"))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	if dt.IssueID == nil || *dt.IssueID != loan.ID {
+		t.Errorf("Expected acceptance to refer to issue %s. Got %v", loan.ID, dt.IssueID)
+	}
+	// Test book costs 1 per day and 1 to replace.
+	lateFee := model.LateFeePerDay * 5
+	surcharge := model.ConditionSurcharges["worn"]
+	if dt.DaysKept != 20 || dt.LateDays != 5 || dt.LateFee != lateFee || dt.Surcharge != surcharge {
+		t.Errorf("Expected 20 days kept, 5 late days, fee %v and surcharge %v. Got %d, %d, %v and %v", lateFee, surcharge, dt.DaysKept, dt.LateDays, dt.LateFee, dt.Surcharge)
+	}
+	if cost := 20 + lateFee + surcharge; dt.FinalCost != cost {
+		t.Errorf("Expected final cost %v. Got %v", cost, dt.FinalCost)
+	}
+	if stock := stockOf(testID); stock != 1 {
+		t.Errorf("Expected the copy back in stock. Got %d", stock)
+	}
+
+	// A closed loan can't be returned again.
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+// Test returning a book in a condition without surcharge.
+// Tests if status code = 400 and the loan stays open.
+func TestReturnIssueWithUnknownCondition(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	response = executeRequest(returnRequest(loan.ID.String(), "soaked"))
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+	if stock := stockOf(testID); stock != 0 {
+		t.Errorf("Expected the copy to stay lent out. Got stock %d", stock)
+	}
+}
+
+// Test updating a loan to a return date before it was issued.
+// Tests if status code = 422 and the return date is kept.
+func TestUpdateIssueReturnBeforeIssue(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     testID,
+		"bookID":     testID,
+		"returnDate": time.Now().AddDate(0, 0, -1).Format(model.DateLayout),
+	})
+	req, _ := http.NewRequest("PUT", "/issue/"+loan.ID.String(), bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+
+	var returnDate string
+	d.Database.QueryRow("SELECT to_char(return_date, 'YYYY-MM-DD') FROM issue WHERE id=$1", loan.ID).Scan(&returnDate)
+	if returnDate != loan.ReturnDate {
+		t.Errorf("Expected return date %s to be kept. Got %s", loan.ReturnDate, returnDate)
+	}
+}
+
+// Test creating acceptance of a lent book with a final cost of the client.
+// Tests if the cost is worked out from the loan and a reader without a
+// loan of the book gets status code = 404.
+func TestCreateAcceptanceFromLoan(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	executeRequest(issueRequest(testID))
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":        testID,
+		"bookID":        testID,
+		"bookCondition": "good",
+		"finalCost":     0.01,
+		"photo":         addImage(),
+	})
+	req, _ := http.NewRequest("POST", "/acceptance", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	// Test book costs 1 per day, kept one day.
+	if dt.IssueID == nil || dt.FinalCost != 1 {
+		t.Errorf("Expected final cost 1 of the loan. Got %v for issue %v", dt.FinalCost, dt.IssueID)
+	}
+
+	req, _ = http.NewRequest("POST", "/acceptance", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+// Helper functions
+
+// Builds request returning a loan in the given condition.
+func returnRequest(issueID, condition string) *http.Request {
+	payload, _ := json.Marshal(map[string]string{"bookCondition": condition, "photo": addImage()})
+	req, _ := http.NewRequest("POST", "/issue/"+issueID+"/return", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+// Builds request issuing the test book to a reader.
+func issueRequest(userID string) *http.Request {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     userID,
+		"bookID":     testID,
+		"returnDate": time.Now().AddDate(0, 0, 14).Format(model.DateLayout),
+	})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+// Adds stock of the test book at the main branch.
+func addStock(count int) {
+	addBranchStock(addBranch("Main"), count)
+}
+
+// Returns number of available copies of a book.
+func stockOf(bookID string) int {
+	var count int
+	d.Database.QueryRow("SELECT COALESCE(SUM(number_of_book), 0) FROM books WHERE book_id=$1", bookID).Scan(&count)
+
+	return count
+}
+
+// Returns number of stored issues.
+func countIssues() int {
+	var count int
+	d.Database.QueryRow("SELECT COUNT(*) FROM issue").Scan(&count)
+
+	return count
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test getting the ledger statement of a reader.
+// Tests if loan fee, late fee and payment are listed with the running balance.
+func TestLedgerStatement(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	// Issued 10 days ago, due 2 days ago.
+	d.Database.Exec("UPDATE issue SET created_at=$1, return_date=$2 WHERE id=$3",
+		time.Now().AddDate(0, 0, -10), time.Now().AddDate(0, 0, -2).Format(model.DateLayout), loan.ID)
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	response = executeRequest(paymentRequest(map[string]interface{}{"userID": testID, "method": model.PaymentCash, "amount": 5}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	req, _ := http.NewRequest("GET", "/user/"+testID+"/statement", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var st model.Statement
+	json.Unmarshal(response.Body.Bytes(), &st)
+	// Loan fee 10 and late fee for 2 days, less the payment.
+	balance := 10 + model.LateFeePerDay*2 - 5
+	if len(st.Entries) != 3 || st.Balance != balance {
+		t.Errorf("Expected 3 entries and balance %v. Got %d entries and %v", balance, len(st.Entries), st.Balance)
+	}
+	if last := st.Entries[len(st.Entries)-1]; last.Kind != model.CreditPayment || last.Amount != -5 || last.Balance != balance {
+		t.Errorf("Expected payment of -5 leaving %v. Got %v", balance, last)
+	}
+
+	req, _ = http.NewRequest("GET", "/user/"+testID, nil)
+	response = executeRequest(req)
+	var user model.User
+	json.Unmarshal(response.Body.Bytes(), &user)
+	if user.Balance != balance {
+		t.Errorf("Expected user balance %v. Got %v", balance, user.Balance)
+	}
+}
+
+// Test getting the readers owing the library money.
+// Tests if only balances above the minimum are listed.
+func TestDebtors(t *testing.T) {
+	clearTable()
+	addUser(1)
+	other := addReader()
+	executeRequest(ledgerRequest(testID, model.ChargeDamage, 12))
+	executeRequest(ledgerRequest(other, model.ChargeLateFee, 3))
+	executeRequest(ledgerRequest(other, model.CreditWaiver, 3))
+
+	req, _ := http.NewRequest("GET", "/debtors?min=1", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var debtors []model.Debtor
+	json.Unmarshal(response.Body.Bytes(), &debtors)
+	if len(debtors) != 1 || debtors[0].UserID.String() != testID || debtors[0].Balance != 12 {
+		t.Errorf("Expected only the test user owing 12. Got %v", debtors)
+	}
+
+	req, _ = http.NewRequest("GET", "/debtors?min=20", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &debtors)
+	if len(debtors) != 0 {
+		t.Errorf("Expected no debtors above 20. Got %v", debtors)
+	}
+}
+
+// Test purging a deleted reader with ledger entries.
+// Tests if the reader and their unpaid charges are kept.
+func TestPurgeReaderWithCharges(t *testing.T) {
+	clearTable()
+	reader := addReader()
+	response := executeRequest(ledgerRequest(reader, model.ChargeDamage, 12))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	timestamp := time.Now()
+	d.Database.Exec("UPDATE users SET deleted_at=$1 WHERE id=$2", timestamp.AddDate(0, 0, -1), reader)
+
+	if _, err := model.PurgeDeleted(d.Database, timestamp); err != nil {
+		t.Fatalf("Expected the purge to skip the reader. Got %v", err)
+	}
+	var entries int
+	d.Database.QueryRow("SELECT count(*) FROM ledger_entries WHERE user_id=$1 AND EXISTS (SELECT 1 FROM users WHERE id=$1)", reader).Scan(&entries)
+	if entries != 1 {
+		t.Errorf("Expected the reader to be kept with the charge. Got %d entries", entries)
+	}
+}
+
+func ledgerRequest(userID, kind string, amount float32) *http.Request {
+	payload, _ := json.Marshal(map[string]interface{}{"kind": kind, "amount": amount, "description": "test " + kind})
+	req, _ := http.NewRequest("POST", "/user/"+userID+"/ledger", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/library/mail"
+	"github.com/library/model"
+)
+
+// Test functions
+
+// Test getting the receipts of a loan and its return.
+// Tests if both are PDFs with the due date and the book condition.
+func TestLoanAndReturnReceipts(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	req, _ := http.NewRequest("GET", "/issue/"+loan.ID.String()+"/receipt", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if ct := response.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Expected application/pdf. Got %s", ct)
+	}
+	if !strings.Contains(response.Body.String(), "Due: "+loan.ReturnDate) {
+		t.Errorf("Expected the due date on the loan receipt")
+	}
+
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	var acceptance model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &acceptance)
+
+	req, _ = http.NewRequest("GET", "/acceptance/"+acceptance.ID.String()+"/receipt", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if !strings.Contains(response.Body.String(), "Condition: good") {
+		t.Errorf("Expected the book condition on the return receipt")
+	}
+}
+
+// Test emailing loan and return receipts.
+// Tests if the reader gets the PDF attached, a refused email gives status
+// code = 502 and an unknown loan 404.
+func TestEmailReceipts(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	var sent []string
+	var sentTo []string
+	mail.SendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, string(msg))
+		sentTo = append(sentTo, to...)
+		return nil
+	}
+	defer func() { mail.SendMail = smtp.SendMail }()
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	req, _ := http.NewRequest("POST", "/issue/"+loan.ID.String()+"/receipt/email", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if len(sent) != 1 || sentTo[0] != "string1" || !strings.Contains(sent[0], "filename=loan-"+loan.ID.String()+".pdf") {
+		t.Errorf("Expected the loan receipt attached to an email to the reader. Got %v to %v", sent, sentTo)
+	}
+
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	var acceptance model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &acceptance)
+	req, _ = http.NewRequest("POST", "/acceptance/"+acceptance.ID.String()+"/receipt/email", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if len(sent) != 2 || !strings.Contains(sent[1], "filename=return-"+acceptance.ID.String()+".pdf") {
+		t.Errorf("Expected the return receipt attached. Got %v", sent)
+	}
+
+	mail.SendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		return errors.New("mailbox unavailable")
+	}
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusBadGateway, response.Code)
+
+	req, _ = http.NewRequest("POST", "/issue/"+testID+"/receipt/email", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+// Test getting the receipt of a non-existent loan.
+// Tests if status code = 404.
+func TestReceiptOfNonExistentIssue(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("GET", "/issue/"+testID+"/receipt", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/library/model"
+)
+
+// Test declaring a loan lost and then found.
+// Tests if the reader is charged for the lost copy and the charges are reversed once found.
+func TestDeclareLostAndFound(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	req, _ := http.NewRequest("POST", "/issue/"+loan.ID.String()+"/lost", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	if dt.ClosedAt == nil || dt.LostAt == nil {
+		t.Errorf("Expected the loan to be closed as lost. Got %v", dt)
+	}
+	if dt.LoanType != loan.LoanType || dt.BranchID == nil || *dt.BranchID != *loan.BranchID {
+		t.Errorf("Expected loan type %s from branch %v. Got %s from %v", loan.LoanType, loan.BranchID, dt.LoanType, dt.BranchID)
+	}
+	// Test book costs 1.
+	if balance := balanceOfUser(testID); balance != 1+model.LostProcessingFee {
+		t.Errorf("Expected balance %v. Got %v", 1+model.LostProcessingFee, balance)
+	}
+	if stock := stockOf(testID); stock != 0 {
+		t.Errorf("Expected the lost copy to stay out of stock. Got %d", stock)
+	}
+
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, _ = http.NewRequest("POST", "/issue/"+loan.ID.String()+"/found", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if balance := balanceOfUser(testID); balance != 0 {
+		t.Errorf("Expected the lost book charges to be reversed. Got balance %v", balance)
+	}
+	if stock := stockOf(testID); stock != 1 {
+		t.Errorf("Expected the found copy back in stock. Got %d", stock)
+	}
+
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+// Test finding a loan that was never lost and losing a non-existent one.
+// Tests if status code = 409 and 404.
+func TestReverseLoanNotLost(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	req, _ := http.NewRequest("POST", "/issue/"+loan.ID.String()+"/found", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, _ = http.NewRequest("POST", "/issue/"+uuid.NewString()+"/lost", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func balanceOfUser(userID string) float32 {
+	req, _ := http.NewRequest("GET", "/user/"+userID+"/statement", nil)
+	response := executeRequest(req)
+	var st model.Statement
+	json.Unmarshal(response.Body.Bytes(), &st)
+
+	return st.Balance
+}
@@ -72,14 +72,28 @@ func ensureTableExists() {
 
 // Clean test tables.
 func clearTable() {
+	// Lending history references users and books, so it goes first.
+	d.Database.Exec("DELETE FROM lending_overrides")
+	d.Database.Exec("DELETE FROM ledger_entries")
+	d.Database.Exec("DELETE FROM payments")
+	d.Database.Exec("DELETE FROM transfers")
+	d.Database.Exec("DELETE FROM acceptance")
+	d.Database.Exec("DELETE FROM issue")
+	d.Database.Exec("DELETE FROM reservations")
+	d.Database.Exec("DELETE FROM lending_policies")
+	d.Database.Exec("DELETE FROM discount_rules")
 	d.Database.Exec("DELETE FROM admins")
 	d.Database.Exec("DELETE FROM users")
 	d.Database.Exec("DELETE FROM categories")
 	d.Database.Exec("DELETE FROM authors")
-	d.Database.Exec("DELETE FROM book")
 	d.Database.Exec("DELETE FROM books")
-	d.Database.Exec("DELETE FROM issue")
-	d.Database.Exec("DELETE FROM acceptance")
+	d.Database.Exec("DELETE FROM opening_hours")
+	d.Database.Exec("DELETE FROM holidays")
+	d.Database.Exec("DELETE FROM branches")
+	d.Database.Exec("DELETE FROM book")
+	d.Database.Exec("DELETE FROM record_versions")
+	d.Database.Exec("DELETE FROM images")
+	d.Database.Exec("DELETE FROM image_blobs")
 }
 
 // SQL query to create table.
@@ -102,7 +116,6 @@ const tableCreationQuery = `
 	    date_of_birth varchar(225) NOT NULL,
 		email varchar(225) NOT NULL,
 	    address varchar(225) NOT NULL,
-	    indebtedness varchar(225) NOT NULL,
 		created_at timestamp NOT NULL,
 		updated_at timestamp NOT NULL,
 		primary key (id)
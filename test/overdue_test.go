@@ -0,0 +1,61 @@
+package test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test getting overdue loans.
+// Tests if days overdue and the fine so far are reported.
+func TestGetOverdueLoans(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(2)
+
+	response := executeRequest(issueRequest(testID))
+	var late model.Issue
+	json.Unmarshal(response.Body.Bytes(), &late)
+	response = executeRequest(issueRequest(testID))
+	var later model.Issue
+	json.Unmarshal(response.Body.Bytes(), &later)
+	// No grace days under the default policy.
+	d.Database.Exec("UPDATE issue SET return_date=$1 WHERE id=$2", time.Now().AddDate(0, 0, -2).Format(model.DateLayout), late.ID)
+	d.Database.Exec("UPDATE issue SET return_date=$1 WHERE id=$2", time.Now().AddDate(0, 0, -5).Format(model.DateLayout), later.ID)
+
+	req, _ := http.NewRequest("GET", "/issuing/overdue", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var loans []model.OverdueLoan
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 2 || loans[0].IssueID != later.ID {
+		t.Fatalf("Expected the loan overdue longest first. Got %v", loans)
+	}
+	if loans[0].DaysOverdue != 5 || loans[0].Fine != 5*model.LateFeePerDay {
+		t.Errorf("Expected 5 days overdue with fine %v. Got %v", 5*model.LateFeePerDay, loans[0])
+	}
+
+	req, _ = http.NewRequest("GET", "/issuing/overdue?field=fine&sort=asc", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 2 || loans[0].IssueID != late.ID {
+		t.Errorf("Expected the smallest fine first. Got %v", loans)
+	}
+
+	req, _ = http.NewRequest("GET", "/issuing/overdue?format=csv", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Errorf("Expected a header and 2 rows. Got %v, %v", records, err)
+	}
+
+	req, _ = http.NewRequest("GET", "/issuing/overdue?field=password", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
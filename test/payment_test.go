@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test paying an acceptance in parts and refunding it.
+// Tests if status code = 409 when paying more than is left.
+func TestPartialPaymentsAndRefund(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET created_at=$1 WHERE id=$2", time.Now().AddDate(0, 0, -10), loan.ID)
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	var acceptance model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &acceptance)
+
+	// Final cost is 10: pay 4 in cash, then 6 by card.
+	response = executeRequest(paymentRequest(map[string]interface{}{"acceptanceID": acceptance.ID, "method": model.PaymentCash, "amount": 4}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var first model.Payment
+	json.Unmarshal(response.Body.Bytes(), &first)
+	if first.Status != model.PaymentSettled || first.ReceiptNumber == nil || first.UserID.String() != testID {
+		t.Fatalf("Expected a settled payment of the test user with a receipt. Got %v", first)
+	}
+
+	response = executeRequest(paymentRequest(map[string]interface{}{"acceptanceID": acceptance.ID, "method": model.PaymentCard, "amount": 7}))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	response = executeRequest(paymentRequest(map[string]interface{}{"acceptanceID": acceptance.ID, "method": model.PaymentCard, "amount": 6}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var second model.Payment
+	json.Unmarshal(response.Body.Bytes(), &second)
+	if second.ReceiptNumber == nil || *second.ReceiptNumber != *first.ReceiptNumber+1 {
+		t.Errorf("Expected receipt number %d. Got %v", *first.ReceiptNumber+1, second.ReceiptNumber)
+	}
+	if profit := getProfit(); profit != 10 {
+		t.Errorf("Expected profit 10. Got %v", profit)
+	}
+
+	// Refund 2 of the card payment.
+	payload, _ := json.Marshal(map[string]interface{}{"amount": 2})
+	req, _ := http.NewRequest("POST", "/payment/"+second.ID.String()+"/refund", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var refund model.Payment
+	json.Unmarshal(response.Body.Bytes(), &refund)
+	if refund.Kind != model.KindRefund || refund.Method != model.PaymentCard || *refund.ReceiptNumber != *second.ReceiptNumber+1 {
+		t.Errorf("Expected a card refund with the next receipt number. Got %v", refund)
+	}
+	if profit := getProfit(); profit != 8 {
+		t.Errorf("Expected profit 8 after refund. Got %v", profit)
+	}
+
+	payload, _ = json.Marshal(map[string]interface{}{"amount": 5})
+	req, _ = http.NewRequest("POST", "/payment/"+second.ID.String()+"/refund", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, _ = http.NewRequest("GET", "/payment/"+refund.ID.String()+"/receipt", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var receipt model.Receipt
+	json.Unmarshal(response.Body.Bytes(), &receipt)
+	if receipt.Number != *refund.ReceiptNumber || receipt.Balance != 2 {
+		t.Errorf("Expected receipt %d leaving balance 2. Got %d and %v", *refund.ReceiptNumber, receipt.Number, receipt.Balance)
+	}
+}
+
+// Test settling a pending card payment.
+// Tests if the payment counts towards profit only once settled.
+func TestPendingCardPayment(t *testing.T) {
+	clearTable()
+	addUser(1)
+	executeRequest(ledgerRequest(testID, model.ChargeDamage, 5))
+
+	response := executeRequest(paymentRequest(map[string]interface{}{"userID": testID, "method": model.PaymentCard, "amount": 5, "status": model.PaymentPending}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var dt model.Payment
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	if dt.Status != model.PaymentPending || dt.ReceiptNumber != nil {
+		t.Errorf("Expected a pending payment without receipt. Got %v", dt)
+	}
+	if profit := getProfit(); profit != 0 {
+		t.Errorf("Expected no profit from pending payments. Got %v", profit)
+	}
+
+	req, _ := http.NewRequest("GET", "/payment/"+dt.ID.String()+"/receipt", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, _ = http.NewRequest("POST", "/payment/"+dt.ID.String()+"/settle", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if profit := getProfit(); profit != 5 {
+		t.Errorf("Expected profit 5 once settled. Got %v", profit)
+	}
+}
+
+// Test paying an acceptance after paying off the balance.
+// Tests if status code = 409 as the balance has nothing left to collect.
+func TestPayAcceptanceAfterBalance(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	response := executeRequest(issueRequest(testID))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	d.Database.Exec("UPDATE issue SET created_at=$1 WHERE id=$2", time.Now().AddDate(0, 0, -10), loan.ID)
+	response = executeRequest(returnRequest(loan.ID.String(), "good"))
+	var acceptance model.Acceptance
+	json.Unmarshal(response.Body.Bytes(), &acceptance)
+
+	response = executeRequest(paymentRequest(map[string]interface{}{"userID": testID, "method": model.PaymentCash, "amount": acceptance.FinalCost}))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	response = executeRequest(paymentRequest(map[string]interface{}{"acceptanceID": acceptance.ID, "method": model.PaymentCash, "amount": 1}))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+func paymentRequest(payment map[string]interface{}) *http.Request {
+	payload, _ := json.Marshal(payment)
+	req, _ := http.NewRequest("POST", "/payment", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func getProfit() float32 {
+	req, _ := http.NewRequest("GET", "/profit", nil)
+	response := executeRequest(req)
+	var profit float32
+	json.Unmarshal(response.Body.Bytes(), &profit)
+
+	return profit
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/model"
+)
+
+// Test functions
+
+// Test issuing a book without return date and cost.
+// Tests if both are taken from the default lending policy.
+func TestIssuePolicyDefaults(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+
+	payload, _ := json.Marshal(map[string]string{"userID": testID, "bookID": testID})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var dt model.Issue
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	period := model.DefaultPolicy.LoanPeriod
+	if due := time.Now().AddDate(0, 0, period).Format(model.DateLayout); dt.ReturnDate != due {
+		t.Errorf("Expected return date %s. Got %s", due, dt.ReturnDate)
+	}
+	// Test book costs 1 per day.
+	if cost := model.DefaultPolicy.LoanCost(1, period); dt.PreliminaryCost != cost {
+		t.Errorf("Expected preliminary cost %v. Got %v", cost, dt.PreliminaryCost)
+	}
+}
+
+// Test issuing more books than a reader type may hold.
+// Tests if status code = 422 and the violated rule is explained.
+func TestIssuePolicyMaxLoans(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(2)
+	addPolicy(map[string]interface{}{"readerType": model.DefaultReaderType, "loanPeriod": 14, "maxLoans": 1, "maxRenewals": 1, "priceMultiplier": 1})
+
+	checkResponseCode(t, http.StatusCreated, executeRequest(issueRequest(testID)).Code)
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+
+	if codes := violationCodes(response.Body.Bytes()); len(codes) != 1 || codes[0] != "max_loans" {
+		t.Errorf("Expected a max_loans violation. Got %v", codes)
+	}
+	if stock := stockOf(testID); stock != 1 {
+		t.Errorf("Expected one copy left in stock. Got %d", stock)
+	}
+}
+
+// Test issuing a book for longer than its category allows.
+// Tests if the category policy wins over the reader type policy.
+func TestIssuePolicyCategory(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	category := uuid.NewString()
+	d.Database.Exec("INSERT INTO categories(id, name, created_at) VALUES($1, $2, $3)", category, "reference", time.Now())
+	d.Database.Exec("INSERT INTO book_categories(book_id, categories_id) VALUES($1, $2)", testID, category)
+	addPolicy(map[string]interface{}{"readerType": model.DefaultReaderType, "loanPeriod": 30, "maxLoans": 5, "priceMultiplier": 1})
+	addPolicy(map[string]interface{}{"categoryID": category, "loanPeriod": 7, "maxLoans": 5, "priceMultiplier": 2})
+
+	// Default issue request asks for 14 days.
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+
+	if codes := violationCodes(response.Body.Bytes()); len(codes) != 1 || codes[0] != "loan_period" {
+		t.Errorf("Expected a loan_period violation. Got %v", codes)
+	}
+}
+
+// Helper functions
+
+// Creates lending policy through the API.
+func addPolicy(policy map[string]interface{}) {
+	payload, _ := json.Marshal(policy)
+	req, _ := http.NewRequest("POST", "/policy", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	executeRequest(req)
+}
+
+// Returns codes of policy violations in a response body.
+func violationCodes(body []byte) []string {
+	var refusal struct {
+		Violations []model.PolicyViolation `json:"violations"`
+	}
+	json.Unmarshal(body, &refusal)
+
+	codes := []string{}
+	for _, v := range refusal.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/library/model"
+)
+
+// Test lending a reading-room only book.
+// Tests if it can only be lent to the reading room for the day and not renewed.
+func TestReadingRoomOnlyBook(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(2)
+	d.Database.Exec("UPDATE book SET reading_room_only=true WHERE id=$1", testID)
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	response = executeRequest(readingRoomRequest(""))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	if loan.LoanType != model.LoanReadingRoom || loan.ReturnDate != time.Now().Format(model.DateLayout) {
+		t.Errorf("Expected a reading-room loan due today. Got %v", loan)
+	}
+
+	response = executeRequest(readingRoomRequest(time.Now().AddDate(0, 0, 1).Format(model.DateLayout)))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, _ := http.NewRequest("POST", "/issue/"+loan.ID.String()+"/renew", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+// Test flagging reading-room loans not returned by closing.
+// Tests if only the reading-room loan is flagged and usage counts both loan types.
+func TestFlagReadingRoomLoans(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(2)
+
+	response := executeRequest(readingRoomRequest(""))
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+	executeRequest(issueRequest(testID))
+
+	flagged, err := model.FlagReadingRoomLoans(d.Database, time.Now().AddDate(0, 0, 1))
+	if err != nil || flagged != 1 {
+		t.Fatalf("Expected the reading-room loan flagged. Got %d, %v", flagged, err)
+	}
+
+	req, _ := http.NewRequest("GET", "/issuing/flagged", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var loans []model.FlaggedLoan
+	json.Unmarshal(response.Body.Bytes(), &loans)
+	if len(loans) != 1 || loans[0].IssueID != loan.ID {
+		t.Errorf("Expected the reading-room loan only. Got %v", loans)
+	}
+
+	req, _ = http.NewRequest("GET", "/books/usage", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var usage []model.BookUsage
+	json.Unmarshal(response.Body.Bytes(), &usage)
+	if len(usage) != 1 || usage[0].HomeLoans != 1 || usage[0].ReadingRoomLoans != 1 || usage[0].TotalLoans != 2 {
+		t.Errorf("Expected one home and one reading-room loan. Got %v", usage)
+	}
+}
+
+// Returns request for a reading-room loan of the test book.
+func readingRoomRequest(returnDate string) *http.Request {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"userID":     testID,
+		"bookID":     testID,
+		"loanType":   model.LoanReadingRoom,
+		"returnDate": returnDate,
+	})
+	req, _ := http.NewRequest("POST", "/issue", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
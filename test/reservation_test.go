@@ -0,0 +1,129 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/library/model"
+)
+
+// Test functions
+
+// Test reserving a book while its only copy is lent out.
+// Tests if readers queue in order and a returned copy is held for the first one.
+func TestReservationQueue(t *testing.T) {
+	clearTable()
+	addUser(1)
+	addBook(1)
+	addStock(1)
+	first, second := addReader(), addReader()
+
+	response := executeRequest(issueRequest(testID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var loan model.Issue
+	json.Unmarshal(response.Body.Bytes(), &loan)
+
+	firstHold := reserve(t, first)
+	secondHold := reserve(t, second)
+	if firstHold.Status != model.ReservationWaiting || firstHold.Position != 1 || secondHold.Position != 2 {
+		t.Errorf("Expected waiting reservations at positions 1 and 2. Got %s at %d and %d", firstHold.Status, firstHold.Position, secondHold.Position)
+	}
+
+	// Returning the copy holds it for the first reader in the queue.
+	req, _ := http.NewRequest("DELETE", "/issue/"+loan.ID.String(), nil)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	if status := reservationStatus(firstHold.ID); status != model.ReservationReady {
+		t.Errorf("Expected first reservation to be ready. Got %s", status)
+	}
+
+	// The held copy can't be issued to anybody else.
+	checkResponseCode(t, http.StatusConflict, executeRequest(issueRequest(second)).Code)
+	checkResponseCode(t, http.StatusCreated, executeRequest(issueRequest(first)).Code)
+	if status := reservationStatus(firstHold.ID); status != model.ReservationFulfilled {
+		t.Errorf("Expected first reservation to be fulfilled. Got %s", status)
+	}
+}
+
+// Test a held copy that is not picked up in time.
+// Tests if the hold expires and the copy goes to the next reader.
+func TestExpireReservation(t *testing.T) {
+	clearTable()
+	addBook(1)
+	addStock(1)
+	first, second := addReader(), addReader()
+
+	firstHold := reserve(t, first)
+	secondHold := reserve(t, second)
+	if firstHold.Status != model.ReservationReady || firstHold.PickupDeadline == nil {
+		t.Errorf("Expected first reservation to hold the copy on the shelf. Got %s", firstHold.Status)
+	}
+
+	expired, err := model.ExpireReservations(d.Database, time.Now().Add(model.HoldPickupPeriod+time.Hour))
+	if err != nil || expired != 1 {
+		t.Errorf("Expected 1 expired reservation. Got %d (%v)", expired, err)
+	}
+	if status := reservationStatus(firstHold.ID); status != model.ReservationExpired {
+		t.Errorf("Expected first reservation to be expired. Got %s", status)
+	}
+	if status := reservationStatus(secondHold.ID); status != model.ReservationReady {
+		t.Errorf("Expected second reservation to be ready. Got %s", status)
+	}
+	if stock := stockOf(testID); stock != 0 {
+		t.Errorf("Expected the copy to stay held. Got stock %d", stock)
+	}
+}
+
+// Test reserving the same book twice.
+// Tests if status code = 409.
+func TestDuplicateReservation(t *testing.T) {
+	clearTable()
+	addBook(1)
+	addStock(0)
+	reader := addReader()
+
+	reserve(t, reader)
+	response := executeRequest(reservationRequest(reader))
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+// Helper functions
+
+// Adds reader for testing and returns its id.
+func addReader() string {
+	id := uuid.NewString()
+	timestamp := time.Now()
+	d.Database.Exec("INSERT INTO users(id, firstname, surname, second_name, passport, date_of_birth, email, address, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", id, "string", "string", "string", id, "string", id+"@gmail.com", "string", timestamp, timestamp)
+
+	return id
+}
+
+// Builds request reserving the test book for a reader.
+func reservationRequest(userID string) *http.Request {
+	payload, _ := json.Marshal(map[string]string{"userID": userID, "bookID": testID})
+	req, _ := http.NewRequest("POST", "/reservation", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+// Reserves the test book for a reader and returns the reservation.
+func reserve(t *testing.T, userID string) model.Reservation {
+	response := executeRequest(reservationRequest(userID))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var dt model.Reservation
+	json.Unmarshal(response.Body.Bytes(), &dt)
+	return dt
+}
+
+// Returns current status of a reservation.
+func reservationStatus(id uuid.UUID) string {
+	var status string
+	d.Database.QueryRow("SELECT status FROM reservations WHERE id=$1", id).Scan(&status)
+
+	return status
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/library/storage"
+	"github.com/spf13/viper"
+)
+
+// Test functions
+
+// Tests local filesystem storage in a temporary directory.
+func TestLocalStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library-images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorage(t, s)
+}
+
+// Tests S3 storage against the local MinIO from docker-compose.
+func TestS3Storage(t *testing.T) {
+	endpoint := viper.GetString("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not configured")
+	}
+	s, err := storage.NewS3(storage.S3Config{
+		Endpoint:  endpoint,
+		Region:    viper.GetString("S3_REGION"),
+		Bucket:    viper.GetString("TEST_S3_BUCKET"),
+		AccessKey: viper.GetString("S3_ACCESS_KEY"),
+		SecretKey: viper.GetString("S3_SECRET_KEY"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorage(t, s)
+}
+
+// Helper functions
+
+// Stores, reads, lists and deletes objects through a storage backend.
+func checkStorage(t *testing.T, s storage.Storage) {
+	names := []string{"1.png", "1_thumbnail.png"}
+	for _, name := range names {
+		if err := s.Put(name, strings.NewReader("content of "+name), int64(len("content of "+name)), "image/png"); err != nil {
+			t.Fatalf("Expected to store %s. Got %s", name, err)
+		}
+	}
+
+	obj, err := s.Get("1.png")
+	if err != nil {
+		t.Fatalf("Expected to read 1.png. Got %s", err)
+	}
+	content, _ := ioutil.ReadAll(obj)
+	obj.Close()
+	if string(content) != "content of 1.png" {
+		t.Errorf("Expected content 'content of 1.png'. Got '%s'", content)
+	}
+
+	listed, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(listed)
+	if strings.Join(listed, ",") != strings.Join(names, ",") {
+		t.Errorf("Expected objects %v. Got %v", names, listed)
+	}
+
+	if err := s.Put("../escape.png", strings.NewReader("x"), 1, "image/png"); err != storage.ErrInvalidName {
+		t.Errorf("Expected name with path separator to be rejected. Got %v", err)
+	}
+
+	for _, name := range names {
+		if err := s.Delete(name); err != nil {
+			t.Errorf("Expected to delete %s. Got %s", name, err)
+		}
+	}
+	if _, err := s.Get("1.png"); err != storage.ErrNotExist {
+		t.Errorf("Expected deleted object to be missing. Got %v", err)
+	}
+}
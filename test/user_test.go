@@ -98,7 +98,6 @@ func TestCreateUser(t *testing.T) {
 		DateOfBirth: "string5",
 		Email: "string6",
 		Address: "string7",
-		Indebtedness: "string8",
 	}
 	payload, err := json.Marshal(newData)
 	if err != nil {
@@ -136,8 +135,8 @@ func TestCreateUser(t *testing.T) {
 	if m["address"] != "string7" {
 		t.Errorf("Expected user address to be 'string7'. Got '%v'", m["address"])
 	}
-	if m["indebtedness"] != "string8" {
-		t.Errorf("Expected user indebtedness to be 'string8'. Got '%v'", m["indebtedness"])
+	if m["balance"] != 0.0 {
+		t.Errorf("Expected new user balance to be 0. Got '%v'", m["balance"])
 	}
 
 }
@@ -159,7 +158,7 @@ func TestUpdateUser(t *testing.T) {
 	var originalUser map[string]interface{}
 	json.Unmarshal(response.Body.Bytes(), &originalUser)
 
-	var jsonStr = []byte(`{"firstName":"string1 - updated firstName", "surname":"string2 - updated surname", "secondName":"string3 - updated secondName", "passport":"string4 - updated passport", "dateOfBirth":"string5 - updated dateOfBirth", "email":"string6 - updated email", "address":"string7 - updated address", "indebtedness":"string8 - updated indebtedness",}`)
+	var jsonStr = []byte(`{"firstName":"string1 - updated firstName", "surname":"string2 - updated surname", "secondName":"string3 - updated secondName", "passport":"string4 - updated passport", "dateOfBirth":"string5 - updated dateOfBirth", "email":"string6 - updated email", "address":"string7 - updated address"}`)
 	req, _ = http.NewRequest("PUT", "/user/"+testID, bytes.NewBuffer(jsonStr))
 	// Add "Token" header to request with generated token.
 	req.Header.Add("Token", validToken)
@@ -200,10 +199,6 @@ func TestUpdateUser(t *testing.T) {
 	if m["address"] == originalUser["address"] {
 		t.Errorf("Expected the address to change from '%v' to '%v'. Got '%v'", originalUser["address"], m["address"], m["address"])
 	}
-
-	if m["indebtedness"] == originalUser["indebtedness"] {
-		t.Errorf("Expected the indebtedness to change from '%v' to '%v'. Got '%v'", originalUser["indebtedness"], m["indebtedness"], m["indebtedness"])
-	}
 }
 
 // Test process of deleting user.
@@ -236,6 +231,60 @@ func TestDeleteUser(t *testing.T) {
 	checkResponseCode(t, http.StatusNotFound, response.Code)
 }
 
+// Test process of restoring a soft deleted user.
+// Tests if deleted user is hidden & comes back after restore.
+func TestRestoreUser(t *testing.T) {
+	clearTable()
+	addUser(1)
+	// Generate JWT for authorization.
+	validToken, err := app.GenerateJWT()
+	if err != nil {
+		t.Error("Failed to generate token")
+	}
+	// Delete user.
+	req, _ := http.NewRequest("DELETE", "/user/"+testID, nil)
+	// Add "Token" header to request with generated token.
+	req.Header.Add("Token", validToken)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	// Check that deleted user is hidden.
+	req, _ = http.NewRequest("GET", "/user/"+testID, nil)
+	req.Header.Add("Token", validToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	// Restore user.
+	req, _ = http.NewRequest("POST", "/user/"+testID+"/restore", nil)
+	req.Header.Add("Token", validToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	// Check that user is visible again.
+	req, _ = http.NewRequest("GET", "/user/"+testID, nil)
+	req.Header.Add("Token", validToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+// Test updating a soft deleted user.
+// Tests if status code = 404 and the user is left unchanged.
+func TestUpdateDeletedUser(t *testing.T) {
+	clearTable()
+	addUser(1)
+	req, _ := http.NewRequest("DELETE", "/user/"+testID, nil)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+
+	var jsonStr = []byte(`{"firstName":"updated", "surname":"updated", "secondName":"updated", "passport":"updated", "dateOfBirth":"updated", "email":"updated@gmail.com", "address":"updated"}`)
+	req, _ = http.NewRequest("PUT", "/user/"+testID, bytes.NewBuffer(jsonStr))
+	req.Header.Set("Content-Type", "application/json")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	var firstname string
+	d.Database.QueryRow("SELECT firstname FROM users WHERE id=$1", testID).Scan(&firstname)
+	if firstname != "string1" {
+		t.Errorf("Expected the deleted user to keep firstname 'string1'. Got '%s'", firstname)
+	}
+}
+
 // Helper functions
 
 // Adds 1 or more records to table for testing.
@@ -246,6 +295,6 @@ func addUser(count int) {
 
 	for i := 1; i <= count; i++ {
 		timestamp := time.Now()
-		d.Database.Exec("INSERT INTO users(id, firstname, surname, second_name, passport, date_of_birth, email, address, indebtedness, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)", testID, "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), timestamp, timestamp)
+		d.Database.Exec("INSERT INTO users(id, firstname, surname, second_name, passport, date_of_birth, email, address, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", testID, "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), "string"+strconv.Itoa(i), timestamp, timestamp)
 	}
 }